@@ -0,0 +1,335 @@
+package mach
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+	"unsafe"
+)
+
+const (
+	BackupStagePrepare = "prepare"
+	BackupStageRunning = "running"
+	BackupStageVerify  = "verify"
+	BackupStageDone    = "done"
+)
+
+// BackupProgress is reported to the callback of BackupOptions and RestoreOptions.
+type BackupProgress struct {
+	Stage   string
+	Path    string
+	Bytes   int64 // size of the files in Path
+	Elapsed time.Duration
+}
+
+type BackupOptions struct {
+	// Table to backup, the whole database if it is empty.
+	Table string
+	// From and To limit the time range of the backup,
+	// zero From means the beginning and zero To means now.
+	// The range is applied in seconds.
+	From time.Time
+	To   time.Time
+	// Progress is called at every stage and every ProgressInterval while running.
+	Progress         func(BackupProgress)
+	ProgressInterval time.Duration
+}
+
+type RestoreOptions struct {
+	// Overwrite replaces the existing database, it is kept until the restored database is verified.
+	Overwrite bool
+	Progress  func(BackupProgress)
+}
+
+// RestoreCheckQuery is run on the restored database to verify it.
+var RestoreCheckQuery = "select count(*) from m$sys_tables"
+
+var backupTableName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_$.]*$`)
+
+// BackupSQL returns the statement that backs up into dest.
+func BackupSQL(dest string, opts BackupOptions) (string, error) {
+	sb := &strings.Builder{}
+	sb.WriteString("BACKUP ")
+	if opts.Table == "" {
+		sb.WriteString("DATABASE")
+	} else {
+		if !backupTableName.MatchString(opts.Table) {
+			return "", ErrBackupInvalidTable(opts.Table)
+		}
+		sb.WriteString("TABLE ")
+		sb.WriteString(opts.Table)
+	}
+	if !opts.From.IsZero() || !opts.To.IsZero() {
+		from, to := opts.From, opts.To
+		if from.IsZero() {
+			from = time.Unix(0, 0)
+		}
+		if to.IsZero() {
+			to = time.Now()
+		}
+		if to.Before(from) {
+			return "", ErrBackupInvalidRange(from, to)
+		}
+		fmt.Fprintf(sb, " FROM TO_DATE('%s', 'YYYY-MM-DD HH24:MI:SS') TO TO_DATE('%s', 'YYYY-MM-DD HH24:MI:SS')",
+			from.In(time.Local).Format(time.DateTime), to.In(time.Local).Format(time.DateTime))
+	}
+	fmt.Fprintf(sb, " INTO DISK = '%s'", strings.ReplaceAll(dest, "'", "''"))
+	return sb.String(), nil
+}
+
+// Backup runs the backup statement of the engine into the directory dest which should not exist.
+// If ctx ends while it is running, the statement is canceled.
+// If it fails, dest is removed, unless the canceled statement does not return in ShutdownCancelGrace.
+func (env *Env) Backup(ctx context.Context, dest string, opts BackupOptions) error {
+	started := time.Now()
+	absDest, err := filepath.Abs(dest)
+	if err != nil {
+		return err
+	}
+	progress := func(stage string) {
+		if opts.Progress != nil {
+			opts.Progress(BackupProgress{Stage: stage, Path: absDest, Bytes: dirSize(absDest), Elapsed: time.Since(started)})
+		}
+	}
+	progress(BackupStagePrepare)
+
+	if _, err := os.Stat(absDest); err == nil {
+		return ErrBackupExists(absDest)
+	}
+	sqlText, err := BackupSQL(absDest, opts)
+	if err != nil {
+		return err
+	}
+
+	conn, err := env.ConnectTrust("sys")
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	stmt, err := conn.NewStmt()
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- stmt.DirectExecute(sqlText)
+	}()
+	interval := opts.ProgressInterval
+	if interval <= 0 {
+		interval = time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	progress(BackupStageRunning)
+	for running := true; running; {
+		select {
+		case err = <-done:
+			running = false
+		case <-ctx.Done():
+			conn.Cancel()
+			select {
+			case dbErr := <-done:
+				err = ErrDatabaseCanceled(ctx.Err(), dbErr)
+			case <-time.After(ShutdownCancelGrace):
+				// the statement is still running, the destination is left to it
+				return ErrDatabaseCanceled(ctx.Err(), ErrConnBusy(conn.sessionID))
+			}
+			running = false
+		case <-ticker.C:
+			progress(BackupStageRunning)
+		}
+	}
+	// the destination did not exist before, so the partial backup is removed
+	if err == nil {
+		progress(BackupStageVerify)
+		err = checkBackupDir(absDest)
+	}
+	if err != nil {
+		os.RemoveAll(absDest)
+		return err
+	}
+	progress(BackupStageDone)
+	return nil
+}
+
+// Restore restores the database from the backup directory src.
+// The database should not be running, and it should not exist unless opts.Overwrite is true.
+// The restored database is verified by starting it up and running RestoreCheckQuery.
+// With opts.Overwrite, the existing database is moved aside during the restore,
+// and it is put back if the restore or the verification fails.
+func (env *Env) Restore(src string, opts RestoreOptions) error {
+	started := time.Now()
+	absSrc, err := filepath.Abs(src)
+	if err != nil {
+		return err
+	}
+	progress := func(stage string) {
+		if opts.Progress != nil {
+			opts.Progress(BackupProgress{Stage: stage, Path: absSrc, Bytes: dirSize(absSrc), Elapsed: time.Since(started)})
+		}
+	}
+	progress(BackupStagePrepare)
+	if err := checkBackupDir(absSrc); err != nil {
+		return err
+	}
+
+	env.mu.Lock()
+	defer env.mu.Unlock()
+	if err := env.check("Restore", EnvStateInitialized, EnvStateCreated, EnvStateStopped); err != nil {
+		return err
+	}
+	dbsDir := env.dbsDir()
+	aside := ""
+	if EngExistsDatabase(env.handle) {
+		if !opts.Overwrite {
+			return ErrBackupDatabaseExists(env.homeDir)
+		}
+		aside = dbsDir + ".restore"
+		if _, err := os.Stat(aside); err == nil {
+			return ErrBackupExists(aside)
+		}
+		if err := os.Rename(dbsDir, aside); err != nil {
+			return err
+		}
+		if err := os.MkdirAll(dbsDir, 0755); err != nil {
+			os.Rename(aside, dbsDir)
+			return err
+		}
+	}
+
+	progress(BackupStageRunning)
+	err = EngRestoreDatabase(env.handle, absSrc)
+	if err == nil {
+		progress(BackupStageVerify)
+		err = env.verifyRestored()
+	}
+	if err != nil {
+		if aside != "" {
+			if e := os.RemoveAll(dbsDir); e != nil {
+				return errors.Join(err, e)
+			}
+			if e := os.Rename(aside, dbsDir); e != nil {
+				return errors.Join(err, e)
+			}
+		}
+		return err
+	}
+	if aside != "" {
+		if err := os.RemoveAll(aside); err != nil {
+			return err
+		}
+	}
+	env.state = EnvStateCreated
+	progress(BackupStageDone)
+	return nil
+}
+
+// dbsDir returns the directory of the database files, the caller should hold the lock.
+func (env *Env) dbsDir() string {
+	conf := env.config
+	if conf == nil {
+		conf = DefaultConfig()
+	}
+	return conf.DbsDir(env.homeDir)
+}
+
+// verifyRestored starts up the restored database, runs RestoreCheckQuery and shuts it down.
+// the caller should hold the lock.
+func (env *Env) verifyRestored() error {
+	handle := env.handle
+	if !EngExistsDatabase(handle) {
+		return ErrEnvDatabaseNotExists(env.homeDir)
+	}
+	if err := EngStartup(handle); err != nil {
+		return err
+	}
+	err := func() error {
+		var conn unsafe.Pointer
+		if err := EngConnectTrust(handle, "sys", &conn); err != nil {
+			return err
+		}
+		defer EngDisconnect(conn)
+		var stmt unsafe.Pointer
+		if err := EngAllocStmt(conn, &stmt); err != nil {
+			return err
+		}
+		defer EngFreeStmt(stmt)
+		if err := EngDirectExecute(stmt, RestoreCheckQuery); err != nil {
+			return err
+		}
+		defer EngExecuteClean(stmt)
+		if exists, err := EngFetch(stmt); err != nil {
+			return err
+		} else if !exists {
+			return ErrDatabaseNoRows(RestoreCheckQuery)
+		}
+		return nil
+	}()
+	return errors.Join(err, EngShutdown(handle))
+}
+
+// checkBackupDir returns error if dir is not a directory, or it does not have any data,
+// or any of the files in it can not be read.
+func checkBackupDir(dir string) error {
+	st, err := os.Stat(dir)
+	if err != nil {
+		return err
+	}
+	if !st.IsDir() {
+		return ErrBackupInvalidDir(dir)
+	}
+	var size int64
+	err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return ErrBackupInvalidDir(path)
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		f.Close()
+		nfo, err := d.Info()
+		if err != nil {
+			return err
+		}
+		size += nfo.Size()
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if size == 0 {
+		return ErrBackupInvalidDir(dir)
+	}
+	return nil
+}
+
+func dirSize(dir string) int64 {
+	var ret int64
+	filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if !d.IsDir() {
+			if nfo, err := d.Info(); err == nil {
+				ret += nfo.Size()
+			}
+		}
+		return nil
+	})
+	return ret
+}
@@ -0,0 +1,74 @@
+package mach_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	mach "github.com/machbase/neo-engine/v8"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackupSQL(t *testing.T) {
+	sqlText, err := mach.BackupSQL("/data/backup", mach.BackupOptions{})
+	require.NoError(t, err)
+	require.Equal(t, `BACKUP DATABASE INTO DISK = '/data/backup'`, sqlText)
+
+	from := time.Date(2021, 1, 1, 0, 0, 0, 0, time.Local)
+	to := time.Date(2021, 1, 2, 0, 0, 0, 0, time.Local)
+	sqlText, err = mach.BackupSQL("/data/it's", mach.BackupOptions{Table: "tag_data", From: from, To: to})
+	require.NoError(t, err)
+	require.Equal(t, `BACKUP TABLE tag_data`+
+		` FROM TO_DATE('2021-01-01 00:00:00', 'YYYY-MM-DD HH24:MI:SS')`+
+		` TO TO_DATE('2021-01-02 00:00:00', 'YYYY-MM-DD HH24:MI:SS')`+
+		` INTO DISK = '/data/it''s'`, sqlText)
+
+	_, err = mach.BackupSQL("/data/backup", mach.BackupOptions{Table: "t; drop table t"})
+	require.Error(t, err)
+	_, err = mach.BackupSQL("/data/backup", mach.BackupOptions{From: to, To: from})
+	require.Error(t, err)
+}
+
+func TestBackup(t *testing.T) {
+	dest := filepath.Join(t.TempDir(), "backup")
+	// the progress reports the absolute path of the relative destination
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	relDest, err := filepath.Rel(wd, dest)
+	require.NoError(t, err)
+	stages := []string{}
+	err = global.Env.Backup(context.Background(), relDest, mach.BackupOptions{
+		Progress: func(p mach.BackupProgress) {
+			require.Equal(t, dest, p.Path)
+			if len(stages) == 0 || stages[len(stages)-1] != p.Stage {
+				stages = append(stages, p.Stage)
+			}
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		mach.BackupStagePrepare,
+		mach.BackupStageRunning,
+		mach.BackupStageVerify,
+		mach.BackupStageDone}, stages)
+
+	// the destination should not exist
+	require.Error(t, global.Env.Backup(context.Background(), dest, mach.BackupOptions{}))
+
+	// the failed backup does not leave the destination
+	failed := filepath.Join(t.TempDir(), "failed")
+	require.Error(t, global.Env.Backup(context.Background(), failed, mach.BackupOptions{Table: "no_such_table"}))
+	require.NoDirExists(t, failed)
+
+	// restore is not allowed while the database is running
+	require.Error(t, global.Env.Restore(dest, mach.RestoreOptions{Overwrite: true}))
+
+	// the backup without data is rejected before the database is touched
+	empty := t.TempDir()
+	require.Error(t, global.Env.Restore(empty, mach.RestoreOptions{Overwrite: true}))
+	require.NoError(t, os.WriteFile(filepath.Join(empty, "empty.dat"), nil, 0644))
+	require.Error(t, global.Env.Restore(empty, mach.RestoreOptions{Overwrite: true}))
+	require.Equal(t, mach.EnvStateStarted, global.Env.State())
+}
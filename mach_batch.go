@@ -0,0 +1,127 @@
+package mach
+
+import (
+	"errors"
+	"fmt"
+	"unsafe"
+)
+
+// BatchPolicy decides what ExecBatch() does when a row fails.
+type BatchPolicy int
+
+const (
+	BatchStopOnError     BatchPolicy = iota // stops at the first failed row
+	BatchContinueOnError                    // executes all the rows
+)
+
+// BatchResult is the result of ExecBatch().
+type BatchResult struct {
+	Executed     int             // rows that were tried, the rest are skipped by BatchStopOnError
+	RowsAffected []int64         // affected rows by the row index, 0 for the failed and skipped rows
+	Errors       []BatchRowError // errors in the order of the row index
+}
+
+// TotalAffected returns the sum of the affected rows.
+func (r *BatchResult) TotalAffected() int64 {
+	var ret int64
+	for _, n := range r.RowsAffected {
+		ret += n
+	}
+	return ret
+}
+
+// Err returns nil if all the rows succeeded, otherwise the joined errors of the rows.
+func (r *BatchResult) Err() error {
+	if len(r.Errors) == 0 {
+		return nil
+	}
+	errs := make([]error, len(r.Errors))
+	for i := range r.Errors {
+		errs[i] = &r.Errors[i]
+	}
+	return errors.Join(errs...)
+}
+
+// BatchRowError is the error of a row of ExecBatch().
+type BatchRowError struct {
+	Row int
+	Err error
+}
+
+func (e *BatchRowError) Error() string {
+	return fmt.Sprintf("MachBatch row %d: %s", e.Row, e.Err.Error())
+}
+
+func (e *BatchRowError) Unwrap() error {
+	return e.Err
+}
+
+// ExecBatch binds each row of the parameters to the prepared engine statement by Bind(),
+// executes and cleans it. The error is the same as BatchResult.Err().
+func ExecBatch(stmt unsafe.Pointer, rows [][]any, policy BatchPolicy) (*BatchResult, error) {
+	return execBatch(rows, policy, func(row []any) (int64, error) {
+		for idx, value := range row {
+			if err := Bind(stmt, idx, value); err != nil {
+				EngExecuteClean(stmt)
+				return 0, err
+			}
+		}
+		if err := EngExecute(stmt); err != nil {
+			EngExecuteClean(stmt)
+			return 0, err
+		}
+		n, err := EngEffectRows(stmt)
+		if cleanErr := EngExecuteClean(stmt); err == nil {
+			err = cleanErr
+		}
+		return n, err
+	})
+}
+
+// ExecBatch is ExecBatch() of the statement.
+func (stmt *Stmt) ExecBatch(rows [][]any, policy BatchPolicy) (*BatchResult, error) {
+	if err := stmt.begin(); err != nil {
+		return nil, err
+	}
+	defer stmt.end()
+	return ExecBatch(stmt.handle, rows, policy)
+}
+
+// CliExecBatch binds each row of the parameters to the prepared CLI statement by CliBind(),
+// executes and cleans it. The error is the same as BatchResult.Err().
+func CliExecBatch(stmt unsafe.Pointer, rows [][]any, policy BatchPolicy) (*BatchResult, error) {
+	return execBatch(rows, policy, func(row []any) (int64, error) {
+		for idx, value := range row {
+			if err := CliBind(stmt, idx, value); err != nil {
+				CliExecuteClean(stmt)
+				return 0, err
+			}
+		}
+		if err := CliExecute(stmt); err != nil {
+			CliExecuteClean(stmt)
+			return 0, err
+		}
+		n, err := CliRowCount(stmt)
+		if cleanErr := CliExecuteClean(stmt); err == nil {
+			err = cleanErr
+		}
+		return n, err
+	})
+}
+
+func execBatch(rows [][]any, policy BatchPolicy, exec func([]any) (int64, error)) (*BatchResult, error) {
+	ret := &BatchResult{RowsAffected: make([]int64, len(rows))}
+	for i, row := range rows {
+		ret.Executed++
+		n, err := exec(row)
+		if err != nil {
+			ret.Errors = append(ret.Errors, BatchRowError{Row: i, Err: err})
+			if policy == BatchStopOnError {
+				break
+			}
+			continue
+		}
+		ret.RowsAffected[i] = n
+	}
+	return ret, ret.Err()
+}
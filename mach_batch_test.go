@@ -0,0 +1,75 @@
+package mach_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"unsafe"
+
+	mach "github.com/machbase/neo-engine/v8"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExecBatch(t *testing.T) {
+	conn, err := global.Env.ConnectTrust("sys")
+	require.NoError(t, err)
+	defer conn.Close()
+	stmt, err := conn.NewStmt()
+	require.NoError(t, err)
+	defer stmt.Close()
+
+	require.NoError(t, stmt.DirectExecute(`create table batch_test (int_value integer, str_value varchar(5))`))
+	defer stmt.DirectExecute(`drop table batch_test`)
+
+	rows := [][]any{{1, "a"}, {2, struct{}{}}, {3, "c"}}
+	require.NoError(t, stmt.Prepare(`insert into batch_test values(?, ?)`))
+	result, err := stmt.ExecBatch(rows, mach.BatchStopOnError)
+	require.Error(t, err)
+	require.Equal(t, 2, result.Executed)
+	require.Equal(t, []int64{1, 0, 0}, result.RowsAffected)
+	require.Len(t, result.Errors, 1)
+	require.Equal(t, 1, result.Errors[0].Row)
+	var rowErr *mach.BatchRowError
+	require.True(t, errors.As(err, &rowErr))
+	require.Equal(t, 1, rowErr.Row)
+
+	result, err = stmt.ExecBatch(rows, mach.BatchContinueOnError)
+	require.Error(t, err)
+	require.Equal(t, 3, result.Executed)
+	require.Equal(t, []int64{1, 0, 1}, result.RowsAffected)
+	require.Equal(t, int64(2), result.TotalAffected())
+
+	result, err = mach.ExecBatch(stmt.Handle(), [][]any{{4, "d"}, {5, nil}}, mach.BatchStopOnError)
+	require.NoError(t, err)
+	require.Nil(t, result.Errors)
+	require.Equal(t, int64(2), result.TotalAffected())
+}
+
+func TestCliExecBatch(t *testing.T) {
+	var conn unsafe.Pointer
+	err := mach.CliConnect(global.CliEnv, fmt.Sprintf("SERVER=127.0.0.1;UID=SYS;PWD=MANAGER;CONNTYPE=1;PORT_NO=%d", machPort), &conn)
+	require.NoError(t, err)
+	defer mach.CliDisconnect(conn)
+
+	require.NoError(t, mach.CliExecDirectConn(conn, `create table cli_batch_test (int_value integer, str_value varchar(5))`))
+	defer mach.CliExecDirectConn(conn, `drop table cli_batch_test`)
+
+	var stmt unsafe.Pointer
+	require.NoError(t, mach.CliAllocStmt(conn, &stmt))
+	defer mach.CliFreeStmt(stmt)
+	require.NoError(t, mach.CliPrepare(stmt, `insert into cli_batch_test values(?, ?)`))
+
+	rows := [][]any{{1, "a"}, {2, "too long"}, {3, "c"}, {4, "d"}}
+	result, err := mach.CliExecBatch(stmt, rows, mach.BatchContinueOnError)
+	require.Error(t, err)
+	require.Equal(t, 4, result.Executed)
+	require.Equal(t, []int64{1, 0, 1, 1}, result.RowsAffected)
+	require.Len(t, result.Errors, 1)
+	var overflow *mach.BindOverflowErr
+	require.True(t, errors.As(err, &overflow))
+
+	result, err = mach.CliExecBatch(stmt, rows, mach.BatchStopOnError)
+	require.Error(t, err)
+	require.Equal(t, 2, result.Executed)
+	require.Equal(t, int64(1), result.TotalAffected())
+}
@@ -0,0 +1,346 @@
+package mach
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"reflect"
+	"slices"
+	"sync"
+	"time"
+	"unsafe"
+)
+
+// Bind binds value to the idx-th parameter of the engine statement by the Go type of value.
+// value can be the integer and float types, bool, string, []byte, time.Time, net.IP,
+// the pointers of them and nil. A nil pointer is bound as NULL.
+// uint64 over math.MaxInt64 is bound by its bits in int64, as the engine takes UINT64.
+//
+// If the types of the parameters are set by EngSetParamDescs(), value is converted and
+// checked against the type of the parameter by BindParam(), and it returns *BindOverflowErr
+// if value is out of the range of the type.
+func Bind(stmt unsafe.Pointer, idx int, value any) error {
+	if desc, ok := engParamDesc(stmt, idx); ok {
+		return BindParam(stmt, idx, value, desc)
+	}
+	v, err := bindNormalize(idx, value)
+	if err != nil {
+		return err
+	}
+	switch val := v.(type) {
+	case nil:
+		return EngBindNull(stmt, idx)
+	case int64:
+		if val >= math.MinInt32 && val <= math.MaxInt32 {
+			return EngBindInt32(stmt, idx, int32(val))
+		}
+		return EngBindInt64(stmt, idx, val)
+	case uint64:
+		if val <= math.MaxInt32 {
+			return EngBindInt32(stmt, idx, int32(val))
+		}
+		return EngBindInt64(stmt, idx, int64(val))
+	case float64:
+		return EngBindFloat64(stmt, idx, val)
+	case string:
+		return EngBindString(stmt, idx, val)
+	case []byte:
+		return EngBindBinary(stmt, idx, val)
+	case time.Time:
+		return EngBindInt64(stmt, idx, val.UnixNano())
+	case net.IP:
+		return EngBindString(stmt, idx, val.String())
+	default:
+		return ErrBindUnsupportedType(idx, value, -1)
+	}
+}
+
+// ParamDesc is the type of a parameter that BindParam() converts and checks the value against.
+type ParamDesc struct {
+	Type ColumnType
+	// Precision is the max length of STRING, 0 means no limit.
+	Precision int
+	Nullable  bool
+}
+
+// ParamDesc returns the parameter type of the CLI description, see SqlType.ColumnType().
+func (desc CliParamDesc) ParamDesc() ParamDesc {
+	return ParamDesc{Type: desc.Type.ColumnType(), Precision: desc.Precision, Nullable: desc.Nullable}
+}
+
+// engParams is the types of the parameters by the engine statement handles.
+var engParams = struct {
+	sync.Mutex
+	stmts map[unsafe.Pointer][]ParamDesc
+}{stmts: map[unsafe.Pointer][]ParamDesc{}}
+
+// EngSetParamDescs sets the types of the parameters of the prepared engine statement,
+// descs[i] is the type of the i-th parameter. Bind() checks the values against them.
+// The engine does not describe the parameters, so they come from the caller,
+// e.g. the columns of the table that the statement inserts into.
+// They are cleared when the statement is prepared again by EngPrepare() or EngDirectExecute(),
+// or freed by EngFreeStmt(). nil descs clears them.
+func EngSetParamDescs(stmt unsafe.Pointer, descs []ParamDesc) {
+	engParams.Lock()
+	defer engParams.Unlock()
+	if len(descs) == 0 {
+		delete(engParams.stmts, stmt)
+		return
+	}
+	engParams.stmts[stmt] = slices.Clone(descs)
+}
+
+// SetParamDescs is EngSetParamDescs() of the statement.
+func (stmt *Stmt) SetParamDescs(descs []ParamDesc) {
+	EngSetParamDescs(stmt.handle, descs)
+}
+
+func engParamDesc(stmt unsafe.Pointer, idx int) (ParamDesc, bool) {
+	engParams.Lock()
+	defer engParams.Unlock()
+	descs := engParams.stmts[stmt]
+	if idx < 0 || idx >= len(descs) {
+		return ParamDesc{}, false
+	}
+	return descs[idx], true
+}
+
+// BindParam binds value to the idx-th parameter of the engine statement as the type of desc.
+// It returns *BindOverflowErr if value is out of the range of the type,
+// and error if value is nil but the parameter is not nullable.
+func BindParam(stmt unsafe.Pointer, idx int, value any, desc ParamDesc) error {
+	v, err := bindConvert(idx, value, desc)
+	if err != nil {
+		return err
+	}
+	switch val := v.(type) {
+	case nil:
+		return EngBindNull(stmt, idx)
+	case int64:
+		switch desc.Type {
+		case MACH_DATA_TYPE_INT16, MACH_DATA_TYPE_INT32, MACH_DATA_TYPE_UINT16:
+			return EngBindInt32(stmt, idx, int32(val))
+		}
+		return EngBindInt64(stmt, idx, val)
+	case float64:
+		return EngBindFloat64(stmt, idx, val)
+	case string:
+		return EngBindString(stmt, idx, val)
+	case []byte:
+		return EngBindBinary(stmt, idx, val)
+	}
+	return ErrBindUnsupportedType(idx, value, desc.Type)
+}
+
+// bindConvert converts value into the value for the parameter of desc.
+// It returns nil for NULL, int64 for the integer types and DATETIME (the bits for UINT64),
+// float64 for FLOAT and DOUBLE, []byte for BINARY and string for the others
+// including DATETIME in string.
+func bindConvert(idx int, value any, desc ParamDesc) (any, error) {
+	typ := desc.Type
+	v, err := bindNormalize(idx, value)
+	if err != nil {
+		return nil, err
+	}
+	if v == nil {
+		if !desc.Nullable {
+			return nil, ErrBindNotNullable(idx, typ)
+		}
+		return nil, nil
+	}
+	switch typ {
+	case MACH_DATA_TYPE_INT16, MACH_DATA_TYPE_INT32, MACH_DATA_TYPE_UINT16,
+		MACH_DATA_TYPE_INT64, MACH_DATA_TYPE_UINT32:
+		return bindInteger(idx, value, v, typ)
+	case MACH_DATA_TYPE_UINT64:
+		// the engine takes the bits of uint64 by int64
+		switch val := v.(type) {
+		case uint64:
+			return int64(val), nil
+		case int64:
+			if val < 0 {
+				return nil, ErrBindOverflow(idx, value, typ)
+			}
+			return val, nil
+		case float64:
+			if val < 0 || val >= math.MaxUint64 || val != math.Trunc(val) {
+				return nil, ErrBindOverflow(idx, value, typ)
+			}
+			return int64(uint64(val)), nil
+		}
+	case MACH_DATA_TYPE_FLOAT, MACH_DATA_TYPE_DOUBLE:
+		var f float64
+		switch val := v.(type) {
+		case int64:
+			f = float64(val)
+		case uint64:
+			f = float64(val)
+		case float64:
+			f = val
+		default:
+			return nil, ErrBindUnsupportedType(idx, value, typ)
+		}
+		if typ == MACH_DATA_TYPE_FLOAT && !math.IsInf(f, 0) && math.Abs(f) > math.MaxFloat32 {
+			return nil, ErrBindOverflow(idx, value, typ)
+		}
+		return f, nil
+	case MACH_DATA_TYPE_DATETIME:
+		switch val := v.(type) {
+		case time.Time:
+			return val.UnixNano(), nil
+		case int64:
+			return val, nil
+		case uint64:
+			if val > math.MaxInt64 {
+				return nil, ErrBindOverflow(idx, value, typ)
+			}
+			return int64(val), nil
+		case string:
+			return val, nil
+		}
+	case MACH_DATA_TYPE_IPV4, MACH_DATA_TYPE_IPV6:
+		var ip net.IP
+		switch val := v.(type) {
+		case net.IP:
+			ip = val
+		case string:
+			if ip = net.ParseIP(val); ip == nil {
+				return nil, ErrBindUnsupportedType(idx, value, typ)
+			}
+		default:
+			return nil, ErrBindUnsupportedType(idx, value, typ)
+		}
+		if typ == MACH_DATA_TYPE_IPV4 {
+			if ip = ip.To4(); ip == nil {
+				return nil, ErrBindUnsupportedType(idx, value, typ)
+			}
+		}
+		return ip.String(), nil
+	case MACH_DATA_TYPE_STRING, MACH_DATA_TYPE_TEXT, MACH_DATA_TYPE_JSON:
+		var s string
+		switch val := v.(type) {
+		case string:
+			s = val
+		case []byte:
+			s = string(val)
+		case net.IP:
+			s = val.String()
+		default:
+			return nil, ErrBindUnsupportedType(idx, value, typ)
+		}
+		if typ == MACH_DATA_TYPE_STRING && desc.Precision > 0 && len(s) > desc.Precision {
+			return nil, ErrBindOverflow(idx, value, typ)
+		}
+		return s, nil
+	case MACH_DATA_TYPE_BINARY:
+		switch val := v.(type) {
+		case []byte:
+			return val, nil
+		case string:
+			return []byte(val), nil
+		}
+	}
+	return nil, ErrBindUnsupportedType(idx, value, typ)
+}
+
+// bindIntegerRange is the range of the integer types.
+var bindIntegerRange = map[ColumnType][2]float64{
+	MACH_DATA_TYPE_INT16:  {math.MinInt16, math.MaxInt16},
+	MACH_DATA_TYPE_UINT16: {0, math.MaxUint16},
+	MACH_DATA_TYPE_INT32:  {math.MinInt32, math.MaxInt32},
+	MACH_DATA_TYPE_UINT32: {0, math.MaxUint32},
+	MACH_DATA_TYPE_INT64:  {math.MinInt64, math.MaxInt64},
+}
+
+// bindInteger converts the normalized value v into int64 in the range of typ.
+func bindInteger(idx int, value any, v any, typ ColumnType) (int64, error) {
+	rng := bindIntegerRange[typ]
+	switch val := v.(type) {
+	case int64:
+		if float64(val) < rng[0] || float64(val) > rng[1] {
+			return 0, ErrBindOverflow(idx, value, typ)
+		}
+		return val, nil
+	case uint64:
+		if val > math.MaxInt64 || float64(val) > rng[1] {
+			return 0, ErrBindOverflow(idx, value, typ)
+		}
+		return int64(val), nil
+	case float64:
+		if val != math.Trunc(val) {
+			return 0, ErrBindUnsupportedType(idx, value, typ)
+		}
+		// float64(math.MaxInt64) rounds up to 2^63
+		if val < rng[0] || val > rng[1] || (typ == MACH_DATA_TYPE_INT64 && val >= math.MaxInt64) {
+			return 0, ErrBindOverflow(idx, value, typ)
+		}
+		return int64(val), nil
+	default:
+		return 0, ErrBindUnsupportedType(idx, value, typ)
+	}
+}
+
+// bindNormalize dereferences the pointers of value and converts it into
+// one of nil, int64, uint64, float64, string, []byte, time.Time and net.IP.
+// bool is converted into int64 0 or 1.
+func bindNormalize(idx int, value any) (any, error) {
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case time.Time, net.IP, []byte, string, int64, uint64, float64:
+		return v, nil
+	case *time.Time:
+		if v == nil {
+			return nil, nil
+		}
+		return *v, nil
+	}
+	rv := reflect.ValueOf(value)
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return nil, nil
+		}
+		rv = rv.Elem()
+	}
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int(), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return rv.Uint(), nil
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), nil
+	case reflect.Bool:
+		if rv.Bool() {
+			return int64(1), nil
+		}
+		return int64(0), nil
+	case reflect.String:
+		return rv.String(), nil
+	case reflect.Struct:
+		if t, ok := rv.Interface().(time.Time); ok {
+			return t, nil
+		}
+	case reflect.Slice:
+		switch b := rv.Interface().(type) {
+		case net.IP:
+			return b, nil
+		case []byte:
+			return b, nil
+		}
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			return rv.Bytes(), nil
+		}
+	}
+	return nil, ErrBindUnsupportedType(idx, value, -1)
+}
+
+// BindOverflowErr is the error that the value is out of the range of the parameter type.
+type BindOverflowErr struct {
+	Idx   int
+	Value any
+	Type  ColumnType
+}
+
+func (e *BindOverflowErr) Error() string {
+	return fmt.Sprintf("MachBind value %v (%T) overflows %s at %d", e.Value, e.Value, e.Type, e.Idx)
+}
@@ -0,0 +1,110 @@
+package mach_test
+
+import (
+	"errors"
+	"math"
+	"net"
+	"testing"
+	"time"
+
+	mach "github.com/machbase/neo-engine/v8"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBind(t *testing.T) {
+	conn, err := global.Env.ConnectTrust("sys")
+	require.NoError(t, err)
+	defer conn.Close()
+	stmt, err := conn.NewStmt()
+	require.NoError(t, err)
+	defer stmt.Close()
+
+	require.NoError(t, stmt.DirectExecute(`create table bind_test (
+		short_value short, ushort_value ushort, int_value integer, uint_value uinteger,
+		long_value long, ulong_value ulong, float_value float, double_value double,
+		time_value datetime, str_value varchar(10), ipv4_value ipv4, ipv6_value ipv6, bin_value binary)`))
+	defer stmt.DirectExecute(`drop table bind_test`)
+
+	now := time.Now()
+	short := int16(-1)
+	var nilPtr *int32
+	require.NoError(t, stmt.Prepare(`insert into bind_test values(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`))
+	values := []any{&short, uint16(2), nilPtr, uint32(4), 5, uint64(6), float32(7.5), 8.5,
+		now, "str", net.IPv4(192, 168, 0, 1), net.IPv6loopback, []byte{1, 2, 3}}
+	for i, v := range values {
+		require.NoError(t, mach.Bind(stmt.Handle(), i, v), "idx %d", i)
+	}
+	require.NoError(t, stmt.Execute())
+	require.NoError(t, stmt.ExecuteClean())
+
+	require.Error(t, mach.Bind(stmt.Handle(), 0, struct{}{}))
+
+	// uint64 over math.MaxInt64 is bound by its bits
+	require.NoError(t, stmt.Prepare(`insert into bind_test(ulong_value) values(?)`))
+	require.NoError(t, mach.Bind(stmt.Handle(), 0, uint64(math.MaxUint64)-1))
+	require.NoError(t, stmt.Execute())
+	require.NoError(t, stmt.ExecuteClean())
+	require.NoError(t, stmt.DirectExecute(`select ulong_value from bind_test where ulong_value is not null order by ulong_value desc limit 1`))
+	exists, err := stmt.Fetch()
+	require.NoError(t, err)
+	require.True(t, exists)
+	ulong, _, err := mach.EngColumnDataUInt64(stmt.Handle(), 0)
+	require.NoError(t, err)
+	require.Equal(t, uint64(math.MaxUint64)-1, ulong)
+	require.NoError(t, stmt.ExecuteClean())
+
+	// Bind checks the values against the parameter types that are set
+	var overflow *mach.BindOverflowErr
+	require.NoError(t, stmt.Prepare(`insert into bind_test(short_value, ulong_value) values(?, ?)`))
+	stmt.SetParamDescs([]mach.ParamDesc{
+		{Type: mach.MACH_DATA_TYPE_INT16, Nullable: true},
+		{Type: mach.MACH_DATA_TYPE_UINT64, Nullable: true},
+	})
+	err = mach.Bind(stmt.Handle(), 0, 32768)
+	require.True(t, errors.As(err, &overflow))
+	require.Equal(t, mach.MACH_DATA_TYPE_INT16, overflow.Type)
+	err = mach.Bind(stmt.Handle(), 1, -1)
+	require.True(t, errors.As(err, &overflow))
+	require.NoError(t, mach.Bind(stmt.Handle(), 0, 32767))
+	require.NoError(t, mach.Bind(stmt.Handle(), 1, uint64(math.MaxUint64)))
+	stmt.SetParamDescs(nil)
+	require.NoError(t, mach.Bind(stmt.Handle(), 0, 32768))
+	// the types are cleared when the statement is prepared again
+	stmt.SetParamDescs([]mach.ParamDesc{{Type: mach.MACH_DATA_TYPE_INT16, Nullable: true}})
+	require.NoError(t, stmt.Prepare(`insert into bind_test(long_value) values(?)`))
+	require.NoError(t, mach.Bind(stmt.Handle(), 0, 32768))
+
+	// check by the parameter metadata
+	tests := []struct {
+		typ      mach.ColumnType
+		value    any
+		overflow bool
+	}{
+		{mach.MACH_DATA_TYPE_INT16, 32767, false},
+		{mach.MACH_DATA_TYPE_INT16, 32768, true},
+		{mach.MACH_DATA_TYPE_INT16, int8(-128), false},
+		{mach.MACH_DATA_TYPE_UINT16, -1, true},
+		{mach.MACH_DATA_TYPE_UINT16, uint16(65535), false},
+		{mach.MACH_DATA_TYPE_INT32, int64(math.MaxInt32) + 1, true},
+		{mach.MACH_DATA_TYPE_UINT32, uint64(math.MaxUint32), false},
+		{mach.MACH_DATA_TYPE_UINT32, uint64(math.MaxUint32) + 1, true},
+		{mach.MACH_DATA_TYPE_INT64, uint64(math.MaxInt64) + 1, true},
+		{mach.MACH_DATA_TYPE_UINT64, uint64(math.MaxUint64), false},
+		{mach.MACH_DATA_TYPE_UINT64, -1, true},
+		{mach.MACH_DATA_TYPE_FLOAT, math.MaxFloat64, true},
+		{mach.MACH_DATA_TYPE_DOUBLE, math.MaxFloat64, false},
+		{mach.MACH_DATA_TYPE_STRING, "12345678901", true},
+	}
+	for _, tt := range tests {
+		desc := mach.ParamDesc{Type: tt.typ, Precision: 10, Nullable: true}
+		err := mach.BindParam(stmt.Handle(), 0, tt.value, desc)
+		if tt.overflow {
+			require.True(t, errors.As(err, &overflow), "%v %v", tt.typ, tt.value)
+		} else {
+			require.NoError(t, err, "%v %v", tt.typ, tt.value)
+		}
+	}
+	require.Error(t, mach.BindParam(stmt.Handle(), 0, nil, mach.ParamDesc{Type: mach.MACH_DATA_TYPE_INT16}))
+	require.Error(t, mach.BindParam(stmt.Handle(), 0, "::1", mach.ParamDesc{Type: mach.MACH_DATA_TYPE_IPV4}))
+	require.Error(t, mach.BindParam(stmt.Handle(), 0, 1.5, mach.ParamDesc{Type: mach.MACH_DATA_TYPE_INT32}))
+}
@@ -0,0 +1,151 @@
+package mach
+
+import (
+	"sync"
+	"unsafe"
+)
+
+// CliBind binds value to the idx-th parameter of the CLI statement.
+// The C type and the SQL type are chosen by the Go type of value and
+// the type of the parameter that CliDescribeParam() reports, the conversions
+// and the range checks are the same as BindParam().
+// machcli.h has no unsigned types, the CLI describes USHORT, UINTEGER and ULONG
+// as the signed types of the same size, so the values over the signed range
+// are rejected by *BindOverflowErr instead of being bound negative.
+// NULL is bound as the type of the parameter with the length MACHCLI_NULL_DATA.
+//
+// The value is copied into C memory that is owned by the statement,
+// so the caller does not need to keep value alive. The memory is kept
+// while the statement is executed, and freed by CliExecuteClean() or CliFreeStmt().
+// Binding the same parameter again frees the memory of the previous value.
+func CliBind(stmt unsafe.Pointer, idx int, value any) error {
+	cliDesc, err := CliDescribeParam(stmt, idx)
+	if err != nil {
+		return err
+	}
+	desc := cliDesc.ParamDesc()
+	v, err := bindConvert(idx, value, desc)
+	if err != nil {
+		return err
+	}
+
+	var cType CType
+	var sqlType SqlType
+	var ptr unsafe.Pointer
+	var length int
+	switch val := v.(type) {
+	case nil:
+		cType, sqlType, length = cliNullCType(cliDesc.Type), cliDesc.Type, MACHCLI_NULL_DATA
+	case int64:
+		switch desc.Type {
+		case MACH_DATA_TYPE_INT16:
+			cType, sqlType, length = MACHCLI_C_TYPE_INT16, MACHCLI_SQL_TYPE_INT16, 2
+			ptr = cMalloc(length)
+			*(*int16)(ptr) = int16(val)
+		case MACH_DATA_TYPE_INT32:
+			cType, sqlType, length = MACHCLI_C_TYPE_INT32, MACHCLI_SQL_TYPE_INT32, 4
+			ptr = cMalloc(length)
+			*(*int32)(ptr) = int32(val)
+		default:
+			cType, sqlType, length = MACHCLI_C_TYPE_INT64, MACHCLI_SQL_TYPE_INT64, 8
+			if desc.Type == MACH_DATA_TYPE_DATETIME {
+				sqlType = MACHCLI_SQL_TYPE_DATETIME
+			}
+			ptr = cMalloc(length)
+			*(*int64)(ptr) = val
+		}
+	case float64:
+		if desc.Type == MACH_DATA_TYPE_FLOAT {
+			cType, sqlType, length = MACHCLI_C_TYPE_FLOAT, MACHCLI_SQL_TYPE_FLOAT, 4
+			ptr = cMalloc(length)
+			*(*float32)(ptr) = float32(val)
+		} else {
+			cType, sqlType, length = MACHCLI_C_TYPE_DOUBLE, MACHCLI_SQL_TYPE_DOUBLE, 8
+			ptr = cMalloc(length)
+			*(*float64)(ptr) = val
+		}
+	case string:
+		cType, sqlType, length = MACHCLI_C_TYPE_CHAR, MACHCLI_SQL_TYPE_STRING, len(val)
+		switch cliDesc.Type {
+		case MACHCLI_SQL_TYPE_DATETIME, MACHCLI_SQL_TYPE_IPV4, MACHCLI_SQL_TYPE_IPV6:
+			sqlType = cliDesc.Type
+		}
+		// terminated by zero, an empty string has a byte to point to
+		ptr = cMalloc(length + 1)
+		copy(unsafe.Slice((*byte)(ptr), length), val)
+	case []byte:
+		cType, sqlType, length = MACHCLI_C_TYPE_BINARY, MACHCLI_SQL_TYPE_BINARY, len(val)
+		ptr = cMalloc(length + 1)
+		copy(unsafe.Slice((*byte)(ptr), length), val)
+	default:
+		return ErrBindUnsupportedType(idx, value, desc.Type)
+	}
+
+	if err := CliBindParam(stmt, idx, cType, sqlType, ptr, length); err != nil {
+		if ptr != nil {
+			cFree(ptr)
+		}
+		return err
+	}
+	cliBindKeep(stmt, idx, ptr)
+	return nil
+}
+
+// MACHCLI_NULL_DATA is the value length of NULL for MachCLIBindParam(), as SQL_NULL_DATA of ODBC.
+const MACHCLI_NULL_DATA = -1
+
+// cliNullCType returns the C type that binds NULL to the parameter of the SQL type.
+func cliNullCType(typ SqlType) CType {
+	switch typ {
+	case MACHCLI_SQL_TYPE_INT16:
+		return MACHCLI_C_TYPE_INT16
+	case MACHCLI_SQL_TYPE_INT32:
+		return MACHCLI_C_TYPE_INT32
+	case MACHCLI_SQL_TYPE_INT64, MACHCLI_SQL_TYPE_DATETIME:
+		return MACHCLI_C_TYPE_INT64
+	case MACHCLI_SQL_TYPE_FLOAT:
+		return MACHCLI_C_TYPE_FLOAT
+	case MACHCLI_SQL_TYPE_DOUBLE:
+		return MACHCLI_C_TYPE_DOUBLE
+	case MACHCLI_SQL_TYPE_BINARY:
+		return MACHCLI_C_TYPE_BINARY
+	default:
+		return MACHCLI_C_TYPE_CHAR
+	}
+}
+
+// cliBinds is the C memory of the bound parameters by the CLI statement handles.
+var cliBinds = struct {
+	sync.Mutex
+	stmts map[unsafe.Pointer]map[int]unsafe.Pointer
+}{stmts: map[unsafe.Pointer]map[int]unsafe.Pointer{}}
+
+// cliBindKeep keeps ptr as the memory of the idx-th parameter of stmt,
+// and frees the memory of the previous value.
+func cliBindKeep(stmt unsafe.Pointer, idx int, ptr unsafe.Pointer) {
+	cliBinds.Lock()
+	defer cliBinds.Unlock()
+	params := cliBinds.stmts[stmt]
+	if params == nil {
+		params = map[int]unsafe.Pointer{}
+		cliBinds.stmts[stmt] = params
+	}
+	if prev := params[idx]; prev != nil {
+		cFree(prev)
+	}
+	if ptr == nil {
+		delete(params, idx)
+	} else {
+		params[idx] = ptr
+	}
+}
+
+// cliBindRelease frees all the memory of the bound parameters of stmt.
+func cliBindRelease(stmt unsafe.Pointer) {
+	cliBinds.Lock()
+	defer cliBinds.Unlock()
+	for _, ptr := range cliBinds.stmts[stmt] {
+		cFree(ptr)
+	}
+	delete(cliBinds.stmts, stmt)
+}
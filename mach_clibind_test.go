@@ -0,0 +1,101 @@
+package mach_test
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"testing"
+	"time"
+	"unsafe"
+
+	mach "github.com/machbase/neo-engine/v8"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCliBind(t *testing.T) {
+	var conn unsafe.Pointer
+	err := mach.CliConnect(global.CliEnv, fmt.Sprintf("SERVER=127.0.0.1;UID=SYS;PWD=MANAGER;CONNTYPE=1;PORT_NO=%d", machPort), &conn)
+	require.NoError(t, err)
+	defer mach.CliDisconnect(conn)
+
+	require.NoError(t, mach.CliExecDirectConn(conn, `create table cli_bind (
+		short_value short, int_value integer, long_value long, float_value float, double_value double,
+		time_value datetime, str_value varchar(10), ipv4_value ipv4, bin_value binary)`))
+	defer mach.CliExecDirectConn(conn, `drop table cli_bind`)
+
+	var stmt unsafe.Pointer
+	require.NoError(t, mach.CliAllocStmt(conn, &stmt))
+	defer mach.CliFreeStmt(stmt)
+	require.NoError(t, mach.CliPrepare(stmt, `insert into cli_bind values(?, ?, ?, ?, ?, ?, ?, ?, ?)`))
+
+	var overflow *mach.BindOverflowErr
+	err = mach.CliBind(stmt, 0, 40000)
+	require.True(t, errors.As(err, &overflow))
+	require.Equal(t, mach.MACH_DATA_TYPE_INT16, overflow.Type)
+	err = mach.CliBind(stmt, 6, "longer than 10")
+	require.True(t, errors.As(err, &overflow))
+	require.Error(t, mach.CliBind(stmt, 7, "::1"))
+
+	now := time.Now()
+	for i := 0; i < 3; i++ {
+		// the values are not alive after binding
+		values := []any{int16(i), i, int64(i), float32(i) + 0.5, float64(i) + 0.5,
+			now, fmt.Sprintf("str-%d", i), net.IPv4(192, 168, 0, byte(i)), []byte{byte(i)}}
+		for idx, v := range values {
+			require.NoError(t, mach.CliBind(stmt, idx, v), "idx %d", idx)
+		}
+		// binding again replaces the previous value
+		require.NoError(t, mach.CliBind(stmt, 1, i*10))
+		require.NoError(t, mach.CliExecute(stmt))
+		require.NoError(t, mach.CliExecuteClean(stmt))
+	}
+	// NULL
+	for idx := 0; idx < 9; idx++ {
+		require.NoError(t, mach.CliBind(stmt, idx, nil))
+	}
+	require.NoError(t, mach.CliExecute(stmt))
+	require.NoError(t, mach.CliExecuteClean(stmt))
+	require.NoError(t, mach.CliExecDirectConn(conn, `EXEC table_flush(cli_bind)`))
+
+	require.NoError(t, mach.CliPrepare(stmt, `select count(*), sum(int_value) from cli_bind where str_value is not null`))
+	require.NoError(t, mach.CliExecute(stmt))
+	end, err := mach.CliFetch(stmt)
+	require.NoError(t, err)
+	require.False(t, end)
+	var count, sum int64
+	_, err = mach.CliGetData(stmt, 0, mach.MACHCLI_C_TYPE_INT64, unsafe.Pointer(&count), 8)
+	require.NoError(t, err)
+	_, err = mach.CliGetData(stmt, 1, mach.MACHCLI_C_TYPE_INT64, unsafe.Pointer(&sum), 8)
+	require.NoError(t, err)
+	require.Equal(t, int64(3), count)
+	require.Equal(t, int64(30), sum)
+	require.NoError(t, mach.CliExecuteClean(stmt))
+
+	require.NoError(t, mach.CliPrepare(stmt, `select count(*) from cli_bind where long_value < ?`))
+	// the CLI describes the parameter as INT64
+	err = mach.CliBind(stmt, 0, uint64(math.MaxInt64)+1)
+	require.True(t, errors.As(err, &overflow))
+	require.NoError(t, mach.CliBind(stmt, 0, uint64(math.MaxInt64)))
+	require.NoError(t, mach.CliExecute(stmt))
+	end, err = mach.CliFetch(stmt)
+	require.NoError(t, err)
+	require.False(t, end)
+	_, err = mach.CliGetData(stmt, 0, mach.MACHCLI_C_TYPE_INT64, unsafe.Pointer(&count), 8)
+	require.NoError(t, err)
+	require.Equal(t, int64(3), count)
+	require.NoError(t, mach.CliExecuteClean(stmt))
+
+	// the row of NULL
+	require.NoError(t, mach.CliPrepare(stmt, `select count(*) from cli_bind where short_value is null and int_value is null
+		and long_value is null and float_value is null and double_value is null and time_value is null
+		and str_value is null and ipv4_value is null and bin_value is null`))
+	require.NoError(t, mach.CliExecute(stmt))
+	end, err = mach.CliFetch(stmt)
+	require.NoError(t, err)
+	require.False(t, end)
+	_, err = mach.CliGetData(stmt, 0, mach.MACHCLI_C_TYPE_INT64, unsafe.Pointer(&count), 8)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), count)
+	require.NoError(t, mach.CliExecuteClean(stmt))
+}
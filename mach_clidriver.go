@@ -0,0 +1,401 @@
+package mach
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"net"
+	"sync"
+	"unsafe"
+)
+
+// CliDriverName is the database/sql driver name of the remote connections over the CLI API.
+// The DSN is the connection string of CliConnect().
+//
+//	db, err := sql.Open(mach.CliDriverName, "SERVER=127.0.0.1;UID=SYS;PWD=MANAGER;CONNTYPE=1;PORT_NO=5656")
+//
+// The driver initializes a CLI env for itself at the first connection, and keeps it
+// for the process so that the connections come and go without setting up the env again.
+// FinalizeCliDriver() finalizes it after all the connections are closed.
+// Use NewCliConnector() to make the connections on your own CLI env.
+const CliDriverName = "machbase-cli"
+
+func init() {
+	sql.Register(CliDriverName, &CliDriver{})
+}
+
+// CliDriver is the database/sql driver over the CLI API.
+type CliDriver struct{}
+
+var _ driver.Driver = (*CliDriver)(nil)
+var _ driver.DriverContext = (*CliDriver)(nil)
+
+// cliDriverEnv is the CLI env of the driver, it is shared by the connections
+// that are not made by NewCliConnector().
+var cliDriverEnv struct {
+	sync.Mutex
+	handle unsafe.Pointer
+	refs   int // open connections on the env
+}
+
+// cliDriverEnvAcquire returns the env of the driver, it initializes the env if there is none.
+// The env should be returned by cliDriverEnvRelease().
+func cliDriverEnvAcquire() (unsafe.Pointer, error) {
+	cliDriverEnv.Lock()
+	defer cliDriverEnv.Unlock()
+	if cliDriverEnv.handle == nil {
+		if err := CliInitialize(&cliDriverEnv.handle); err != nil {
+			cliDriverEnv.handle = nil
+			return nil, err
+		}
+	}
+	cliDriverEnv.refs++
+	return cliDriverEnv.handle, nil
+}
+
+// cliDriverEnvRelease returns the env of the driver, the env is kept even if it is the last reference.
+func cliDriverEnvRelease() {
+	cliDriverEnv.Lock()
+	defer cliDriverEnv.Unlock()
+	cliDriverEnv.refs--
+}
+
+// FinalizeCliDriver finalizes the CLI env of CliDriverName, the next connection initializes a new one.
+// It returns error if any connection of the driver is open.
+func FinalizeCliDriver() error {
+	cliDriverEnv.Lock()
+	defer cliDriverEnv.Unlock()
+	if cliDriverEnv.handle == nil {
+		return nil
+	}
+	if cliDriverEnv.refs > 0 {
+		return ErrCliDriverBusy(cliDriverEnv.refs)
+	}
+	handle := cliDriverEnv.handle
+	cliDriverEnv.handle = nil
+	return CliFinalize(handle)
+}
+
+func (d *CliDriver) Open(dsn string) (driver.Conn, error) {
+	connector, err := d.OpenConnector(dsn)
+	if err != nil {
+		return nil, err
+	}
+	return connector.Connect(context.Background())
+}
+
+func (d *CliDriver) OpenConnector(dsn string) (driver.Connector, error) {
+	if _, err := parseDSN(dsn); err != nil {
+		return nil, err
+	}
+	return &cliConnector{dsn: dsn}, nil
+}
+
+// NewCliConnector returns the database/sql connector that connects to dsn on the CLI env.
+// The env should not be finalized while the connections are open.
+func NewCliConnector(env unsafe.Pointer, dsn string) driver.Connector {
+	return &cliConnector{env: env, dsn: dsn}
+}
+
+type cliConnector struct {
+	env unsafe.Pointer // nil means the env of the driver
+	dsn string
+}
+
+func (c *cliConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	env, driverEnv := c.env, c.env == nil
+	if driverEnv {
+		var err error
+		if env, err = cliDriverEnvAcquire(); err != nil {
+			return nil, err
+		}
+	}
+	var handle unsafe.Pointer
+	if err := CliConnect(env, c.dsn, &handle); err != nil {
+		if driverEnv {
+			cliDriverEnvRelease()
+		}
+		return nil, err
+	}
+	return &cliConn{handle: handle, driverEnv: driverEnv}, nil
+}
+
+func (c *cliConnector) Driver() driver.Driver {
+	return &CliDriver{}
+}
+
+type cliConn struct {
+	handle    unsafe.Pointer
+	driverEnv bool // the connection is on the env of the driver
+	broken    bool
+	closed    bool
+}
+
+var _ driver.Conn = (*cliConn)(nil)
+var _ driver.ConnPrepareContext = (*cliConn)(nil)
+var _ driver.ExecerContext = (*cliConn)(nil)
+var _ driver.QueryerContext = (*cliConn)(nil)
+var _ driver.Pinger = (*cliConn)(nil)
+var _ driver.Validator = (*cliConn)(nil)
+var _ driver.NamedValueChecker = (*cliConn)(nil)
+
+// observe marks the connection broken if it can not be used after err,
+// then database/sql discards the connection by IsValid().
+// It returns err as it is, since the statement might have been done on the server.
+func (c *cliConn) observe(err error) error {
+	if !c.broken && cliConnBroken(c.handle, err) {
+		c.broken = true
+	}
+	return err
+}
+
+func (c *cliConn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+// PrepareContext prepares query, `:name` and `@name` placeholders are bound by sql.Named() arguments.
+// If query has them, it is prepared at the execution when the arguments are known,
+// since they are rewritten only for sql.Named() arguments.
+func (c *cliConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	var handle unsafe.Pointer
+	if err := CliAllocStmt(c.handle, &handle); err != nil {
+		return nil, c.observe(err)
+	}
+	ret := &cliStmt{conn: c, handle: handle, query: newDriverQuery(query)}
+	if !ret.query.deferred() {
+		if _, err := ret.prepare(ctx, nil); err != nil {
+			CliFreeStmt(handle)
+			return nil, c.observe(err)
+		}
+	}
+	return ret, nil
+}
+
+func (c *cliConn) Close() error {
+	if c.closed {
+		return nil
+	}
+	c.closed = true
+	err := CliDisconnect(c.handle)
+	if c.driverEnv {
+		cliDriverEnvRelease()
+	}
+	return err
+}
+
+// Begin returns error, the server does not support transactions.
+func (c *cliConn) Begin() (driver.Tx, error) {
+	return nil, ErrDriverTxNotSupported()
+}
+
+func (c *cliConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	s, err := c.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer s.Close()
+	return s.(*cliStmt).ExecContext(ctx, args)
+}
+
+func (c *cliConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	s, err := c.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.(*cliStmt).QueryContext(ctx, args)
+	if err != nil {
+		s.Close()
+		return nil, err
+	}
+	// the statement is freed with the rows
+	rows.(*cliRows).closeStmt = true
+	return rows, nil
+}
+
+func (c *cliConn) Ping(ctx context.Context) error {
+	if c.broken {
+		return driver.ErrBadConn
+	}
+	var handle unsafe.Pointer
+	if err := CliAllocStmt(c.handle, &handle); err != nil {
+		return c.observe(err)
+	}
+	defer CliFreeStmt(handle)
+	err := runContext(ctx, cliCancelFunc(handle), func() error {
+		if err := CliExecDirect(handle, CliPingQuery); err != nil {
+			return err
+		}
+		_, err := CliFetch(handle)
+		return err
+	})
+	return c.observe(err)
+}
+
+func (c *cliConn) IsValid() bool {
+	return !c.broken
+}
+
+// CheckNamedValue converts net.IP to string, the others are converted by database/sql.
+func (c *cliConn) CheckNamedValue(nv *driver.NamedValue) error {
+	switch v := nv.Value.(type) {
+	case net.IP:
+		nv.Value = v.String()
+		return nil
+	default:
+		return driver.ErrSkip
+	}
+}
+
+type cliStmt struct {
+	conn   *cliConn
+	handle unsafe.Pointer
+	query  *driverQuery
+}
+
+var _ driver.Stmt = (*cliStmt)(nil)
+var _ driver.StmtExecContext = (*cliStmt)(nil)
+var _ driver.StmtQueryContext = (*cliStmt)(nil)
+
+func (s *cliStmt) Close() error {
+	return CliFreeStmt(s.handle)
+}
+
+// NumInput returns -1 if the query has named placeholders,
+// since a name can be used more than once and the query is not prepared yet.
+func (s *cliStmt) NumInput() int {
+	if s.query.deferred() {
+		return -1
+	}
+	if n, err := CliNumParam(s.handle); err == nil {
+		return n
+	}
+	return -1
+}
+
+func (s *cliStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.ExecContext(context.Background(), namedValues(args))
+}
+
+func (s *cliStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.QueryContext(context.Background(), namedValues(args))
+}
+
+func (s *cliStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	if err := s.bind(ctx, args); err != nil {
+		return nil, s.conn.observe(err)
+	}
+	defer s.clean()
+	if err := runContext(ctx, cliCancelFunc(s.handle), func() error { return CliExecute(s.handle) }); err != nil {
+		return nil, s.conn.observe(err)
+	}
+	n, err := CliRowCount(s.handle)
+	if err != nil {
+		return nil, s.conn.observe(err)
+	}
+	return driver.RowsAffected(n), nil
+}
+
+func (s *cliStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	if err := s.bind(ctx, args); err != nil {
+		return nil, s.conn.observe(err)
+	}
+	if err := runContext(ctx, cliCancelFunc(s.handle), func() error { return CliExecute(s.handle) }); err != nil {
+		s.clean()
+		return nil, s.conn.observe(err)
+	}
+	columns, err := CliColumns(s.handle)
+	if err != nil {
+		s.clean()
+		return nil, s.conn.observe(err)
+	}
+	rows := &cliRows{stmt: s, ctx: ctx, resultColumns: resultColumns{columns: columns}}
+	return rows, nil
+}
+
+func (s *cliStmt) clean() {
+	CliExecuteClean(s.handle)
+}
+
+// prepare prepares the query for args if the statement is not prepared with it yet,
+// it returns args in the order of the placeholders.
+func (s *cliStmt) prepare(ctx context.Context, args []driver.NamedValue) ([]driver.NamedValue, error) {
+	sqlText, args, err := s.query.resolve(args)
+	if err != nil {
+		return nil, err
+	}
+	if sqlText != s.query.prepared {
+		s.query.prepared = ""
+		if err := runContext(ctx, cliCancelFunc(s.handle), func() error { return CliPrepare(s.handle, sqlText) }); err != nil {
+			return nil, err
+		}
+		s.query.prepared = sqlText
+	}
+	return args, nil
+}
+
+func (s *cliStmt) bind(ctx context.Context, args []driver.NamedValue) error {
+	args, err := s.prepare(ctx, args)
+	if err != nil {
+		return err
+	}
+	for _, arg := range args {
+		if err := CliBind(s.handle, arg.Ordinal-1, arg.Value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type cliRows struct {
+	resultColumns
+	bufs      [][]byte // the buffers of the columns for cliColumnValue()
+	stmt      *cliStmt
+	ctx       context.Context
+	closeStmt bool
+}
+
+var _ driver.Rows = (*cliRows)(nil)
+var _ driver.RowsColumnTypeScanType = (*cliRows)(nil)
+var _ driver.RowsColumnTypeDatabaseTypeName = (*cliRows)(nil)
+var _ driver.RowsColumnTypeLength = (*cliRows)(nil)
+var _ driver.RowsColumnTypeNullable = (*cliRows)(nil)
+
+func (r *cliRows) Close() error {
+	r.stmt.clean()
+	if r.closeStmt {
+		return r.stmt.Close()
+	}
+	return nil
+}
+
+func (r *cliRows) Next(dest []driver.Value) error {
+	end, err := CliFetchContext(r.ctx, r.stmt.handle)
+	if err != nil {
+		return r.stmt.conn.observe(err)
+	}
+	if end {
+		return io.EOF
+	}
+	if r.bufs == nil {
+		r.bufs = make([][]byte, len(r.columns))
+	}
+	for i := range dest {
+		value, valid, err := cliColumnValue(r.stmt.handle, i, r.columns[i], &r.bufs[i])
+		if err != nil {
+			return r.stmt.conn.observe(err)
+		}
+		if !valid {
+			dest[i] = nil
+			continue
+		}
+		dest[i] = driverValue(value)
+	}
+	return nil
+}
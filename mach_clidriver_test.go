@@ -0,0 +1,99 @@
+package mach_test
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	mach "github.com/machbase/neo-engine/v8"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCliDriver(t *testing.T) {
+	dsn := fmt.Sprintf("SERVER=127.0.0.1;UID=SYS;PWD=MANAGER;CONNTYPE=1;PORT_NO=%d", machPort)
+	db, err := sql.Open(mach.CliDriverName, dsn)
+	require.NoError(t, err)
+	defer db.Close()
+	require.NoError(t, db.Ping())
+
+	_, err = db.Exec(`create table driver_cli (time datetime, short_value short, int_value integer, long_value long,
+		double_value double, str_value varchar(40), ipv4_value ipv4, ipv6_value ipv6)`)
+	require.NoError(t, err)
+	defer db.Exec(`drop table driver_cli`)
+
+	now := time.Now().Truncate(time.Millisecond)
+	_, err = db.Exec(`insert into driver_cli (time, short_value, int_value, long_value, double_value, str_value, ipv4_value)
+		values(?, ?, ?, ?, ?, ?, ?)`,
+		now, 1, 2, 3, 4.5, "cli-driver", net.IPv4(192, 168, 0, 1))
+	require.NoError(t, err)
+	_, err = db.Exec(`EXEC table_flush(driver_cli)`)
+	require.NoError(t, err)
+
+	// the same application code with the connector on the existing CLI env
+	db2 := sql.OpenDB(mach.NewCliConnector(global.CliEnv, dsn))
+	defer db2.Close()
+	for _, d := range []*sql.DB{db, db2} {
+		var ts time.Time
+		var short int16
+		var i32 int32
+		var i64 int64
+		var dbl float64
+		var str string
+		var ip string
+		var ipv6 sql.NullString
+		err = d.QueryRow(`select time, short_value, int_value, long_value, double_value, str_value, ipv4_value, ipv6_value
+			from driver_cli where str_value = ?`, "cli-driver").Scan(&ts, &short, &i32, &i64, &dbl, &str, &ip, &ipv6)
+		require.NoError(t, err)
+		require.Equal(t, now.UnixNano(), ts.UnixNano())
+		require.Equal(t, int16(1), short)
+		require.Equal(t, int32(2), i32)
+		require.Equal(t, int64(3), i64)
+		require.Equal(t, 4.5, dbl)
+		require.Equal(t, "cli-driver", str)
+		require.Equal(t, "192.168.0.1", ip)
+		require.False(t, ipv6.Valid)
+	}
+
+	// named parameters
+	var count int
+	err = db.QueryRow(`select count(*) from driver_cli where str_value = :str and int_value = :int`,
+		sql.Named("int", 2), sql.Named("str", "cli-driver")).Scan(&count)
+	require.NoError(t, err)
+	require.Equal(t, 1, count)
+
+	rows, err := db.Query(`select short_value, str_value from driver_cli`)
+	require.NoError(t, err)
+	colTypes, err := rows.ColumnTypes()
+	require.NoError(t, err)
+	require.Equal(t, "SHORT", colTypes[0].DatabaseTypeName())
+	require.Equal(t, "VARCHAR", colTypes[1].DatabaseTypeName())
+	_, ok := colTypes[1].Nullable()
+	require.True(t, ok)
+	require.NoError(t, rows.Close())
+
+	_, err = sql.Open(mach.CliDriverName, "SERVER")
+	require.Error(t, err)
+}
+
+func TestCliDriverEnv(t *testing.T) {
+	dsn := fmt.Sprintf("SERVER=127.0.0.1;UID=SYS;PWD=MANAGER;CONNTYPE=1;PORT_NO=%d", machPort)
+	db, err := sql.Open(mach.CliDriverName, dsn)
+	require.NoError(t, err)
+	// every connection is closed after it is used
+	db.SetMaxIdleConns(0)
+	require.NoError(t, db.Ping())
+	cliEnvs := mach.CliEnvCount()
+	require.NoError(t, db.Ping())
+	require.Equal(t, cliEnvs, mach.CliEnvCount())
+
+	conn, err := db.Conn(context.Background())
+	require.NoError(t, err)
+	require.Error(t, mach.FinalizeCliDriver())
+	require.NoError(t, conn.Close())
+	require.NoError(t, db.Close())
+	require.NoError(t, mach.FinalizeCliDriver())
+	require.Equal(t, cliEnvs-1, mach.CliEnvCount())
+}
@@ -0,0 +1,226 @@
+package mach
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+	"unsafe"
+)
+
+// CliPingQuery is the query of the default validation of CliPool.
+var CliPingQuery = "select count(*) from m$sys_users"
+
+// CliPingTimeout bounds the ping of cliConnBroken(), the connection that does not answer
+// in time is broken. It is canceled by CliCancel() since a dead link may not return the call.
+var CliPingTimeout = 5 * time.Second
+
+// CliNetworkErrorCodes are the MACHCLI-ERR codes that IsCliNetworkError() reports true.
+// machcli.h does not define the codes of the network errors, so it is empty by default
+// and the applications add the codes of their client library.
+// Without them, the broken connections are found by pinging with CliPingQuery.
+var CliNetworkErrorCodes = map[int]bool{}
+
+// cliStmtLevelFns are the CLI functions of which the errors are of the statement,
+// e.g. the syntax error or the table not found, not of the connection.
+var cliStmtLevelFns = []string{
+	"MachCLIPrepare", "MachCLIExecDirect", "MachCliExecDirectConn", "MachCLIExecute(",
+	"MachCLIExplain", "MachCLIBindParam", "MachCLIDescribeParam", "MachCLIAppendOpen",
+}
+
+// IsCliNetworkError returns true if err is a MACHCLI-ERR of one of CliNetworkErrorCodes,
+// the connection that returns it can not be used anymore.
+func IsCliNetworkError(err error) bool {
+	var cliErr *CliErr
+	if !errors.As(err, &cliErr) {
+		return false
+	}
+	return CliNetworkErrorCodes[cliErr.Code]
+}
+
+// cliConnBroken returns true if the connection can not be used anymore after err.
+// It is true for IsCliNetworkError(). The other MACHCLI-ERR that are not of the statement
+// (see cliStmtLevelFns) cost a round trip of CliPingQuery, and it is true if the ping fails.
+func cliConnBroken(conn unsafe.Pointer, err error) bool {
+	var cliErr *CliErr
+	if err == nil || !errors.As(err, &cliErr) {
+		return false
+	}
+	if CliNetworkErrorCodes[cliErr.Code] {
+		return true
+	}
+	for _, fn := range cliStmtLevelFns {
+		if strings.HasPrefix(cliErr.Fn, fn) {
+			return false
+		}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), CliPingTimeout)
+	defer cancel()
+	return cliPingContext(ctx, conn) != nil
+}
+
+type CliPoolOptions struct {
+	// MaxOpen limits the connections that are open, 0 means unlimited.
+	MaxOpen int
+	// MaxIdle limits the idle connections, 0 means DefaultPoolMaxIdle and negative means no idle connection.
+	MaxIdle int
+	// IdleTimeout closes the connections that are idle longer than it, 0 means no timeout.
+	IdleTimeout time.Duration
+	// MaxLifetime closes the connections that are older than it, 0 means no limit.
+	MaxLifetime time.Duration
+	// Validate is called on an idle connection before it is checked out,
+	// the connection is closed if it returns error. Nil means pinging with CliPingQuery.
+	Validate func(conn unsafe.Pointer) error
+}
+
+// CliPool keeps the CLI connections that are made by the connection string for reuse.
+type CliPool struct {
+	env     unsafe.Pointer
+	connStr string
+	opts    CliPoolOptions
+	core    *poolCore[*CliPoolConn]
+}
+
+// CliPoolConn is a connection that is checked out from CliPool,
+// it should be returned by Release().
+type CliPoolConn struct {
+	poolItem
+	handle unsafe.Pointer
+	pool   *CliPool
+}
+
+// NewCliPool creates a pool of the connections to connStr on the CLI env.
+// The pool should be closed before CliFinalize() of the env.
+func NewCliPool(env unsafe.Pointer, connStr string, opts CliPoolOptions) *CliPool {
+	if opts.Validate == nil {
+		opts.Validate = cliPing
+	}
+	ret := &CliPool{env: env, connStr: connStr, opts: opts}
+	ret.core = newPoolCore(poolConfig{
+		MaxOpen:     opts.MaxOpen,
+		MaxIdle:     opts.MaxIdle,
+		IdleTimeout: opts.IdleTimeout,
+		MaxLifetime: opts.MaxLifetime,
+	}, poolHooks[*CliPoolConn]{
+		connect: ret.connect,
+		close:   func(pc *CliPoolConn) { CliDisconnect(pc.handle) },
+		check: func(pc *CliPoolConn) error {
+			err := opts.Validate(pc.handle)
+			if IsCliNetworkError(err) {
+				pc.pool.core.markBroken(pc)
+			}
+			return err
+		},
+		closedErr: ErrCliPoolClosed,
+	})
+	return ret
+}
+
+// Get checks out an idle connection or opens a new one.
+// If MaxOpen connections are open, it waits until one is released or ctx ends.
+func (pool *CliPool) Get(ctx context.Context) (*CliPoolConn, error) {
+	return pool.core.get(ctx)
+}
+
+func (pool *CliPool) connect() (*CliPoolConn, error) {
+	var handle unsafe.Pointer
+	if err := CliConnect(pool.env, pool.connStr, &handle); err != nil {
+		return nil, err
+	}
+	return &CliPoolConn{handle: handle, pool: pool}, nil
+}
+
+func (pc *CliPoolConn) Handle() unsafe.Pointer {
+	return pc.handle
+}
+
+func (pc *CliPoolConn) CreatedAt() time.Time {
+	return pc.createdAt
+}
+
+// Observe checks the error that the connection returned and returns it as it is.
+// If the connection is broken by it, the connection is evicted when it is released.
+// The error that is not of the statement is checked by a round trip of CliPingQuery.
+// The methods of CliPoolConn observe their errors by themselves, Observe() is for
+// the calls on Handle().
+//
+//	err := pc.Observe(mach.CliExecDirectConn(pc.Handle(), sqlText))
+func (pc *CliPoolConn) Observe(err error) error {
+	if cliConnBroken(pc.handle, err) {
+		pc.pool.core.markBroken(pc)
+	}
+	return err
+}
+
+// ExecDirect is CliExecDirectConn() on the connection.
+func (pc *CliPoolConn) ExecDirect(sqlText string) error {
+	return pc.Observe(CliExecDirectConn(pc.handle, sqlText))
+}
+
+// Execute is CliExecuteConn() on the connection.
+func (pc *CliPoolConn) Execute(ctx context.Context, sqlText string, args ...any) (*ExecuteResult, error) {
+	ret, err := CliExecuteConn(ctx, pc.handle, sqlText, args...)
+	return ret, pc.Observe(err)
+}
+
+// Prepare allocates a statement on the connection and prepares sqlText.
+// The statement should be freed by CliFreeStmt().
+func (pc *CliPoolConn) Prepare(sqlText string) (unsafe.Pointer, error) {
+	var stmt unsafe.Pointer
+	if err := CliAllocStmt(pc.handle, &stmt); err != nil {
+		return nil, pc.Observe(err)
+	}
+	if err := CliPrepare(stmt, sqlText); err != nil {
+		CliFreeStmt(stmt)
+		return nil, pc.Observe(err)
+	}
+	return stmt, nil
+}
+
+// Release returns the connection to the pool.
+// The connection is closed instead if it is broken,
+// or the pool is closed or has enough idle connections.
+// It is safe to call Release() more than once.
+func (pc *CliPoolConn) Release() {
+	pc.pool.core.release(pc)
+}
+
+// Discard closes the connection instead of returning it to the pool.
+func (pc *CliPoolConn) Discard() {
+	pc.pool.core.drop(pc)
+}
+
+// Stats returns the counters of the pool, Sessions is always empty.
+func (pool *CliPool) Stats() PoolStats {
+	return pool.core.snapshot(nil)
+}
+
+// Close closes the idle connections and the pool,
+// the connections in use are closed when they are released.
+func (pool *CliPool) Close() error {
+	pool.core.shutdown()
+	return nil
+}
+
+func cliPing(conn unsafe.Pointer) error {
+	return cliPingContext(context.Background(), conn)
+}
+
+func cliPingContext(ctx context.Context, conn unsafe.Pointer) error {
+	var stmt unsafe.Pointer
+	if err := CliAllocStmt(conn, &stmt); err != nil {
+		return err
+	}
+	defer CliFreeStmt(stmt)
+	return runContext(ctx, cliCancelFunc(stmt), func() error {
+		if err := CliExecDirect(stmt, CliPingQuery); err != nil {
+			return err
+		}
+		if end, err := CliFetch(stmt); err != nil {
+			return err
+		} else if end {
+			return ErrDatabaseNoRows(CliPingQuery)
+		}
+		return nil
+	})
+}
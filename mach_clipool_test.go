@@ -0,0 +1,88 @@
+package mach_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+	"unsafe"
+
+	mach "github.com/machbase/neo-engine/v8"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsCliNetworkError(t *testing.T) {
+	require.False(t, mach.IsCliNetworkError(nil))
+	require.False(t, mach.IsCliNetworkError(errors.New("communication link failure")))
+	require.False(t, mach.IsCliNetworkError(mach.ErrDatabaseCli("MachCLIExecDirect()", 2024, "Table not found")))
+	// the messages are not matched
+	require.False(t, mach.IsCliNetworkError(mach.ErrDatabaseCli("MachCLIExecDirect()", 0, "Connection timeout of the rollup")))
+
+	mach.CliNetworkErrorCodes[9999] = true
+	defer delete(mach.CliNetworkErrorCodes, 9999)
+	require.True(t, mach.IsCliNetworkError(mach.ErrDatabaseCli("MachCLIFetch()", 9999, "unknown")))
+	require.True(t, mach.IsCliNetworkError(fmt.Errorf("wrapped %w", mach.ErrDatabaseCli("MachCLIFetch()", 9999, "unknown"))))
+
+	var cliErr *mach.CliErr
+	require.True(t, errors.As(mach.ErrDatabaseCli("MachCLIFetch()", 9999, "unknown"), &cliErr))
+	require.Equal(t, "MACHCLI-ERR 9999 unknown, MachCLIFetch()", cliErr.Error())
+}
+
+func TestCliPool(t *testing.T) {
+	connStr := fmt.Sprintf("SERVER=127.0.0.1;UID=SYS;PWD=MANAGER;CONNTYPE=1;PORT_NO=%d", machPort)
+	validateErr := error(nil)
+	pool := mach.NewCliPool(global.CliEnv, connStr, mach.CliPoolOptions{
+		MaxOpen: 2,
+		Validate: func(conn unsafe.Pointer) error {
+			return validateErr
+		},
+	})
+	defer pool.Close()
+	ctx := context.Background()
+
+	c1, err := pool.Get(ctx)
+	require.NoError(t, err)
+	require.NoError(t, c1.ExecDirect("select count(*) from m$sys_users"))
+	c1.Release()
+
+	c2, err := pool.Get(ctx)
+	require.NoError(t, err)
+	require.Equal(t, c1.Handle(), c2.Handle())
+	c3, err := pool.Get(ctx)
+	require.NoError(t, err)
+
+	timeoutCtx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	_, err = pool.Get(timeoutCtx)
+	cancel()
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// an error of the statement on the live connection does not evict it
+	require.Error(t, c3.ExecDirect("select * from no_such_table"))
+	// the other error is checked by the ping, the live connection answers it
+	fetchErr := mach.ErrDatabaseCli("MachCLIFetch()", 1, "unknown")
+	require.Equal(t, fetchErr, c3.Observe(fetchErr))
+	// a network error evicts the connection
+	mach.CliNetworkErrorCodes[9999] = true
+	defer delete(mach.CliNetworkErrorCodes, 9999)
+	netErr := mach.ErrDatabaseCli("MachCLIExecDirect()", 9999, "Communication link failure")
+	require.Equal(t, netErr, c2.Observe(netErr))
+	c2.Release()
+	c3.Release()
+	stats := pool.Stats()
+	require.Equal(t, int64(1), stats.NetworkErrorClosed)
+	require.Equal(t, 1, stats.Open)
+	require.Equal(t, 1, stats.Idle)
+
+	// validation on borrow
+	validateErr = errors.New("invalid")
+	c4, err := pool.Get(ctx)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), pool.Stats().HealthCheckFailed)
+	c4.Release()
+
+	require.NoError(t, pool.Close())
+	require.Equal(t, 0, pool.Stats().Open)
+	_, err = pool.Get(ctx)
+	require.Error(t, err)
+}
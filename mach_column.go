@@ -0,0 +1,352 @@
+package mach
+
+import (
+	"bytes"
+	"net"
+	"reflect"
+	"time"
+	"unsafe"
+)
+
+// ColumnType is the data type of a column, see MACH_DATA_TYPE_XXX in machEngine.h.
+// The CLI reports the types by SqlType that has no unsigned, TEXT and JSON types,
+// see SqlType.ColumnType().
+type ColumnType int
+
+const (
+	MACH_DATA_TYPE_INT16    ColumnType = 0
+	MACH_DATA_TYPE_INT32    ColumnType = 1
+	MACH_DATA_TYPE_INT64    ColumnType = 2
+	MACH_DATA_TYPE_DATETIME ColumnType = 3
+	MACH_DATA_TYPE_FLOAT    ColumnType = 4
+	MACH_DATA_TYPE_DOUBLE   ColumnType = 5
+	MACH_DATA_TYPE_IPV4     ColumnType = 6
+	MACH_DATA_TYPE_IPV6     ColumnType = 7
+	MACH_DATA_TYPE_STRING   ColumnType = 8
+	MACH_DATA_TYPE_BINARY   ColumnType = 9
+	MACH_DATA_TYPE_UINT16   ColumnType = 10
+	MACH_DATA_TYPE_UINT32   ColumnType = 11
+	MACH_DATA_TYPE_UINT64   ColumnType = 12
+	MACH_DATA_TYPE_TEXT     ColumnType = 13
+	MACH_DATA_TYPE_JSON     ColumnType = 14
+)
+
+var columnTypeNames = [...]string{
+	MACH_DATA_TYPE_INT16:    "INT16",
+	MACH_DATA_TYPE_INT32:    "INT32",
+	MACH_DATA_TYPE_INT64:    "INT64",
+	MACH_DATA_TYPE_DATETIME: "DATETIME",
+	MACH_DATA_TYPE_FLOAT:    "FLOAT",
+	MACH_DATA_TYPE_DOUBLE:   "DOUBLE",
+	MACH_DATA_TYPE_IPV4:     "IPV4",
+	MACH_DATA_TYPE_IPV6:     "IPV6",
+	MACH_DATA_TYPE_STRING:   "STRING",
+	MACH_DATA_TYPE_BINARY:   "BINARY",
+	MACH_DATA_TYPE_UINT16:   "UINT16",
+	MACH_DATA_TYPE_UINT32:   "UINT32",
+	MACH_DATA_TYPE_UINT64:   "UINT64",
+	MACH_DATA_TYPE_TEXT:     "TEXT",
+	MACH_DATA_TYPE_JSON:     "JSON",
+}
+
+// the names in CREATE TABLE
+var columnTypeDatabaseNames = [...]string{
+	MACH_DATA_TYPE_INT16:    "SHORT",
+	MACH_DATA_TYPE_INT32:    "INTEGER",
+	MACH_DATA_TYPE_INT64:    "LONG",
+	MACH_DATA_TYPE_DATETIME: "DATETIME",
+	MACH_DATA_TYPE_FLOAT:    "FLOAT",
+	MACH_DATA_TYPE_DOUBLE:   "DOUBLE",
+	MACH_DATA_TYPE_IPV4:     "IPV4",
+	MACH_DATA_TYPE_IPV6:     "IPV6",
+	MACH_DATA_TYPE_STRING:   "VARCHAR",
+	MACH_DATA_TYPE_BINARY:   "BINARY",
+	MACH_DATA_TYPE_UINT16:   "USHORT",
+	MACH_DATA_TYPE_UINT32:   "UINTEGER",
+	MACH_DATA_TYPE_UINT64:   "ULONG",
+	MACH_DATA_TYPE_TEXT:     "TEXT",
+	MACH_DATA_TYPE_JSON:     "JSON",
+}
+
+var columnScanTypes = [...]reflect.Type{
+	MACH_DATA_TYPE_INT16:    reflect.TypeOf(int16(0)),
+	MACH_DATA_TYPE_INT32:    reflect.TypeOf(int32(0)),
+	MACH_DATA_TYPE_INT64:    reflect.TypeOf(int64(0)),
+	MACH_DATA_TYPE_DATETIME: reflect.TypeOf(time.Time{}),
+	MACH_DATA_TYPE_FLOAT:    reflect.TypeOf(float32(0)),
+	MACH_DATA_TYPE_DOUBLE:   reflect.TypeOf(float64(0)),
+	MACH_DATA_TYPE_IPV4:     reflect.TypeOf(net.IP{}),
+	MACH_DATA_TYPE_IPV6:     reflect.TypeOf(net.IP{}),
+	MACH_DATA_TYPE_STRING:   reflect.TypeOf(""),
+	MACH_DATA_TYPE_BINARY:   reflect.TypeOf([]byte{}),
+	MACH_DATA_TYPE_UINT16:   reflect.TypeOf(uint16(0)),
+	MACH_DATA_TYPE_UINT32:   reflect.TypeOf(uint32(0)),
+	MACH_DATA_TYPE_UINT64:   reflect.TypeOf(uint64(0)),
+	MACH_DATA_TYPE_TEXT:     reflect.TypeOf(""),
+	MACH_DATA_TYPE_JSON:     reflect.TypeOf(""),
+}
+
+func (typ ColumnType) Valid() bool {
+	return typ >= MACH_DATA_TYPE_INT16 && typ <= MACH_DATA_TYPE_JSON
+}
+
+func (typ ColumnType) String() string {
+	if !typ.Valid() {
+		return "UNKNOWN"
+	}
+	return columnTypeNames[typ]
+}
+
+// DatabaseTypeName returns the name of the type in SQL, e.g. "VARCHAR" for MACH_DATA_TYPE_STRING.
+func (typ ColumnType) DatabaseTypeName() string {
+	if !typ.Valid() {
+		return "UNKNOWN"
+	}
+	return columnTypeDatabaseNames[typ]
+}
+
+// ScanType returns the Go type of the column value, nil if the type is unknown.
+func (typ ColumnType) ScanType() reflect.Type {
+	if !typ.Valid() {
+		return nil
+	}
+	return columnScanTypes[typ]
+}
+
+// Variable returns true if the values of the type have variable length.
+func (typ ColumnType) Variable() bool {
+	switch typ {
+	case MACH_DATA_TYPE_STRING, MACH_DATA_TYPE_BINARY, MACH_DATA_TYPE_TEXT, MACH_DATA_TYPE_JSON:
+		return true
+	default:
+		return false
+	}
+}
+
+// ColumnType returns the column type of the CLI type.
+// The CLI reports USHORT, UINTEGER and ULONG by the signed types of the same size,
+// TEXT and JSON by MACHCLI_SQL_TYPE_STRING. It returns -1 for an unknown type.
+func (typ SqlType) ColumnType() ColumnType {
+	switch typ {
+	case MACHCLI_SQL_TYPE_INT16:
+		return MACH_DATA_TYPE_INT16
+	case MACHCLI_SQL_TYPE_INT32:
+		return MACH_DATA_TYPE_INT32
+	case MACHCLI_SQL_TYPE_INT64:
+		return MACH_DATA_TYPE_INT64
+	case MACHCLI_SQL_TYPE_DATETIME:
+		return MACH_DATA_TYPE_DATETIME
+	case MACHCLI_SQL_TYPE_FLOAT:
+		return MACH_DATA_TYPE_FLOAT
+	case MACHCLI_SQL_TYPE_DOUBLE:
+		return MACH_DATA_TYPE_DOUBLE
+	case MACHCLI_SQL_TYPE_IPV4:
+		return MACH_DATA_TYPE_IPV4
+	case MACHCLI_SQL_TYPE_IPV6:
+		return MACH_DATA_TYPE_IPV6
+	case MACHCLI_SQL_TYPE_STRING:
+		return MACH_DATA_TYPE_STRING
+	case MACHCLI_SQL_TYPE_BINARY:
+		return MACH_DATA_TYPE_BINARY
+	default:
+		return -1
+	}
+}
+
+// Column is the metadata of a result column.
+type Column struct {
+	Name string
+	Type ColumnType
+	// Size is the byte size of the fixed types, or the max length of the variable types.
+	Size int
+	// Length is the length that the engine reports, it is 0 on the CLI path.
+	Length int
+	// Nullable is valid if NullableKnown is true, the engine does not report nullability.
+	Nullable      bool
+	NullableKnown bool
+}
+
+// EngColumns returns the result columns of the executed statement.
+// The engine does not report nullability, so NullableKnown is false.
+func EngColumns(stmt unsafe.Pointer) ([]Column, error) {
+	count, err := EngColumnCount(stmt)
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]Column, count)
+	for i := range ret {
+		var typ int
+		col := &ret[i]
+		if err := EngColumnInfo(stmt, i, &col.Name, &typ, &col.Size, &col.Length); err != nil {
+			return nil, err
+		}
+		col.Type = ColumnType(typ)
+	}
+	return ret, nil
+}
+
+// CliColumns returns the result columns of the executed statement.
+func CliColumns(stmt unsafe.Pointer) ([]Column, error) {
+	count, err := CliNumResultCol(stmt)
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]Column, count)
+	for i := range ret {
+		var typ SqlType
+		var scale int
+		col := &ret[i]
+		if err := CliDescribeCol(stmt, i, &col.Name, &typ, &col.Size, &scale, &col.Nullable); err != nil {
+			return nil, err
+		}
+		col.Type = typ.ColumnType()
+		col.NullableKnown = true
+	}
+	return ret, nil
+}
+
+// engColumnValue returns the value of the column of the fetched row in the Go type of the column,
+// and false if the value is NULL.
+func engColumnValue(stmt unsafe.Pointer, idx int) (any, bool, error) {
+	typ, _, err := EngColumnType(stmt, idx)
+	if err != nil {
+		return nil, false, err
+	}
+	switch ColumnType(typ) {
+	case MACH_DATA_TYPE_INT16:
+		return EngColumnDataInt16(stmt, idx)
+	case MACH_DATA_TYPE_INT32:
+		return EngColumnDataInt32(stmt, idx)
+	case MACH_DATA_TYPE_INT64:
+		return EngColumnDataInt64(stmt, idx)
+	case MACH_DATA_TYPE_DATETIME:
+		return EngColumnDataDateTime(stmt, idx)
+	case MACH_DATA_TYPE_FLOAT:
+		return EngColumnDataFloat32(stmt, idx)
+	case MACH_DATA_TYPE_DOUBLE:
+		return EngColumnDataFloat64(stmt, idx)
+	case MACH_DATA_TYPE_IPV4:
+		return EngColumnDataIPv4(stmt, idx)
+	case MACH_DATA_TYPE_IPV6:
+		return EngColumnDataIPv6(stmt, idx)
+	case MACH_DATA_TYPE_STRING, MACH_DATA_TYPE_TEXT, MACH_DATA_TYPE_JSON:
+		return EngColumnDataString(stmt, idx)
+	case MACH_DATA_TYPE_BINARY:
+		return EngColumnDataBinary(stmt, idx)
+	case MACH_DATA_TYPE_UINT16:
+		return EngColumnDataUInt16(stmt, idx)
+	case MACH_DATA_TYPE_UINT32:
+		return EngColumnDataUInt32(stmt, idx)
+	case MACH_DATA_TYPE_UINT64:
+		return EngColumnDataUInt64(stmt, idx)
+	default:
+		return nil, false, ErrDatabaseUnsupportedType("EngColumnValue", typ)
+	}
+}
+
+// cliColumnBufferSize is the initial size of the buffer of cliColumnValue()
+// for the columns of the variable length.
+const cliColumnBufferSize = 1024
+
+// cliColumnValue returns the value of the column of the fetched row in the Go type of the column,
+// and false if the value is NULL.
+// buf is the buffer of the column that is reused for the rows, it grows to the longest value.
+func cliColumnValue(stmt unsafe.Pointer, idx int, col Column, buf *[]byte) (any, bool, error) {
+	switch col.Type {
+	case MACH_DATA_TYPE_INT16, MACH_DATA_TYPE_UINT16:
+		var v int16
+		if n, err := CliGetData(stmt, idx, MACHCLI_C_TYPE_INT16, unsafe.Pointer(&v), 2); err != nil || n < 0 {
+			return nil, false, err
+		}
+		if col.Type == MACH_DATA_TYPE_UINT16 {
+			return uint16(v), true, nil
+		}
+		return v, true, nil
+	case MACH_DATA_TYPE_INT32, MACH_DATA_TYPE_UINT32:
+		var v int32
+		if n, err := CliGetData(stmt, idx, MACHCLI_C_TYPE_INT32, unsafe.Pointer(&v), 4); err != nil || n < 0 {
+			return nil, false, err
+		}
+		if col.Type == MACH_DATA_TYPE_UINT32 {
+			return uint32(v), true, nil
+		}
+		return v, true, nil
+	case MACH_DATA_TYPE_INT64, MACH_DATA_TYPE_UINT64:
+		var v int64
+		if n, err := CliGetData(stmt, idx, MACHCLI_C_TYPE_INT64, unsafe.Pointer(&v), 8); err != nil || n < 0 {
+			return nil, false, err
+		}
+		if col.Type == MACH_DATA_TYPE_UINT64 {
+			return uint64(v), true, nil
+		}
+		return v, true, nil
+	case MACH_DATA_TYPE_DATETIME:
+		var v int64
+		if n, err := CliGetData(stmt, idx, MACHCLI_C_TYPE_INT64, unsafe.Pointer(&v), 8); err != nil || n < 0 {
+			return nil, false, err
+		}
+		return time.Unix(0, v), true, nil
+	case MACH_DATA_TYPE_FLOAT:
+		var v float32
+		if n, err := CliGetData(stmt, idx, MACHCLI_C_TYPE_FLOAT, unsafe.Pointer(&v), 4); err != nil || n < 0 {
+			return nil, false, err
+		}
+		return v, true, nil
+	case MACH_DATA_TYPE_DOUBLE:
+		var v float64
+		if n, err := CliGetData(stmt, idx, MACHCLI_C_TYPE_DOUBLE, unsafe.Pointer(&v), 8); err != nil || n < 0 {
+			return nil, false, err
+		}
+		return v, true, nil
+	case MACH_DATA_TYPE_IPV4, MACH_DATA_TYPE_IPV6:
+		buf := make([]byte, 64)
+		n, err := CliGetData(stmt, idx, MACHCLI_C_TYPE_CHAR, unsafe.Pointer(&buf[0]), len(buf))
+		if err != nil || n < 0 {
+			return nil, false, err
+		}
+		return net.ParseIP(string(buf[:n])), true, nil
+	case MACH_DATA_TYPE_STRING, MACH_DATA_TYPE_TEXT, MACH_DATA_TYPE_JSON:
+		data, valid, err := cliGetVarData(stmt, idx, col, MACHCLI_C_TYPE_CHAR, buf)
+		if err != nil || !valid {
+			return nil, false, err
+		}
+		return string(data), true, nil
+	case MACH_DATA_TYPE_BINARY:
+		data, valid, err := cliGetVarData(stmt, idx, col, MACHCLI_C_TYPE_BINARY, buf)
+		if err != nil || !valid {
+			return nil, false, err
+		}
+		return bytes.Clone(data), true, nil
+	default:
+		return nil, false, ErrDatabaseUnsupportedType("CliColumnValue", int(col.Type))
+	}
+}
+
+// cliGetVarData reads the value of the string or binary column into buf.
+// If the value is longer than buf, MachCLIGetData() reports the whole length,
+// then buf grows to it and the value is read again.
+// It returns error instead of the truncated value.
+func cliGetVarData(stmt unsafe.Pointer, idx int, col Column, cType CType, buf *[]byte) ([]byte, bool, error) {
+	// the string is terminated by zero
+	pad := 0
+	if cType == MACHCLI_C_TYPE_CHAR {
+		pad = 1
+	}
+	if len(*buf) == 0 {
+		*buf = make([]byte, min(max(col.Size, 1), cliColumnBufferSize)+pad)
+	}
+	n, err := CliGetData(stmt, idx, cType, unsafe.Pointer(&(*buf)[0]), len(*buf))
+	if err != nil || n < 0 {
+		return nil, false, err
+	}
+	if int(n)+pad > len(*buf) {
+		*buf = make([]byte, int(n)+pad)
+		if n, err = CliGetData(stmt, idx, cType, unsafe.Pointer(&(*buf)[0]), len(*buf)); err != nil || n < 0 {
+			return nil, false, err
+		}
+		if int(n)+pad > len(*buf) {
+			return nil, false, ErrCliDataTruncated(col.Name, int(n), len(*buf)-pad)
+		}
+	}
+	return (*buf)[:n], true, nil
+}
@@ -0,0 +1,53 @@
+package mach_test
+
+import (
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	mach "github.com/machbase/neo-engine/v8"
+	"github.com/stretchr/testify/require"
+)
+
+func TestColumnType(t *testing.T) {
+	tests := []struct {
+		typ      mach.ColumnType
+		name     string
+		dbName   string
+		scanType reflect.Type
+		variable bool
+	}{
+		{mach.MACH_DATA_TYPE_INT16, "INT16", "SHORT", reflect.TypeOf(int16(0)), false},
+		{mach.MACH_DATA_TYPE_INT32, "INT32", "INTEGER", reflect.TypeOf(int32(0)), false},
+		{mach.MACH_DATA_TYPE_INT64, "INT64", "LONG", reflect.TypeOf(int64(0)), false},
+		{mach.MACH_DATA_TYPE_DATETIME, "DATETIME", "DATETIME", reflect.TypeOf(time.Time{}), false},
+		{mach.MACH_DATA_TYPE_FLOAT, "FLOAT", "FLOAT", reflect.TypeOf(float32(0)), false},
+		{mach.MACH_DATA_TYPE_DOUBLE, "DOUBLE", "DOUBLE", reflect.TypeOf(float64(0)), false},
+		{mach.MACH_DATA_TYPE_IPV4, "IPV4", "IPV4", reflect.TypeOf(net.IP{}), false},
+		{mach.MACH_DATA_TYPE_IPV6, "IPV6", "IPV6", reflect.TypeOf(net.IP{}), false},
+		{mach.MACH_DATA_TYPE_STRING, "STRING", "VARCHAR", reflect.TypeOf(""), true},
+		{mach.MACH_DATA_TYPE_BINARY, "BINARY", "BINARY", reflect.TypeOf([]byte{}), true},
+		{mach.MACH_DATA_TYPE_UINT16, "UINT16", "USHORT", reflect.TypeOf(uint16(0)), false},
+		{mach.MACH_DATA_TYPE_UINT32, "UINT32", "UINTEGER", reflect.TypeOf(uint32(0)), false},
+		{mach.MACH_DATA_TYPE_UINT64, "UINT64", "ULONG", reflect.TypeOf(uint64(0)), false},
+		{mach.MACH_DATA_TYPE_TEXT, "TEXT", "TEXT", reflect.TypeOf(""), true},
+		{mach.MACH_DATA_TYPE_JSON, "JSON", "JSON", reflect.TypeOf(""), true},
+	}
+	for i, tt := range tests {
+		require.Equal(t, mach.ColumnType(i), tt.typ)
+		require.True(t, tt.typ.Valid())
+		require.Equal(t, tt.name, tt.typ.String())
+		require.Equal(t, tt.dbName, tt.typ.DatabaseTypeName())
+		require.Equal(t, tt.scanType, tt.typ.ScanType())
+		require.Equal(t, tt.variable, tt.typ.Variable())
+	}
+	for _, typ := range []mach.ColumnType{-1, 15} {
+		require.False(t, typ.Valid())
+		require.Equal(t, "UNKNOWN", typ.String())
+		require.Nil(t, typ.ScanType())
+	}
+	require.Equal(t, mach.MACH_DATA_TYPE_DOUBLE, mach.MACHCLI_SQL_TYPE_DOUBLE.ColumnType())
+	require.Equal(t, mach.MACH_DATA_TYPE_BINARY, mach.MACHCLI_SQL_TYPE_BINARY.ColumnType())
+	require.Equal(t, mach.ColumnType(-1), mach.SqlType(10).ColumnType())
+}
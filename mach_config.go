@@ -0,0 +1,323 @@
+package mach
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+const (
+	ConfigFileName  = "machbase.conf"
+	ConfigHomeToken = "?"
+)
+
+const (
+	sizeMB = 1024 * 1024
+	sizeGB = 1024 * sizeMB
+)
+
+// Config is the properties of machbase.conf.
+//
+// The fields are tagged with the property name (conf) and
+// the valid range (min, max) that is checked by Validate().
+// Properties that are not covered by the fields are kept in Extra.
+type Config struct {
+	PortNo        int64  `conf:"PORT_NO" min:"1" max:"65535"`
+	BindIPAddress string `conf:"BIND_IP_ADDRESS"`
+	DbsPath       string `conf:"DBS_PATH"`
+
+	TraceLogfileSize  int64  `conf:"TRACE_LOGFILE_SIZE" min:"1"`
+	TraceLogfileCount int64  `conf:"TRACE_LOGFILE_COUNT" min:"1"`
+	TraceLogfilePath  string `conf:"TRACE_LOGFILE_PATH"`
+	TraceLogLevel     int64  `conf:"TRACE_LOG_LEVEL" min:"0"`
+
+	DurationGap int64 `conf:"DURATION_GAP" min:"0"`
+	CPUParallel int64 `conf:"CPU_PARALLEL" min:"1"`
+
+	DiskColumnarTableCheckpointIntervalSec      int64 `conf:"DISK_COLUMNAR_TABLE_CHECKPOINT_INTERVAL_SEC" min:"1" max:"4294967295"`
+	DiskColumnarIndexCheckpointIntervalSec      int64 `conf:"DISK_COLUMNAR_INDEX_CHECKPOINT_INTERVAL_SEC" min:"1" max:"4294967295"`
+	DiskColumnarTableColumnPartFlushMode        int64 `conf:"DISK_COLUMNAR_TABLE_COLUMN_PART_FLUSH_MODE" min:"0" max:"1"`
+	DiskColumnarTableColumnPartIOIntervalMinSec int64 `conf:"DISK_COLUMNAR_TABLE_COLUMN_PART_IO_INTERVAL_MIN_SEC" min:"0" max:"4294967295"`
+	DiskIOThreadCount                           int64 `conf:"DISK_IO_THREAD_COUNT" min:"1" max:"4294967295"`
+	DiskColumnarTablespaceMemoryMinSize         int64 `conf:"DISK_COLUMNAR_TABLESPACE_MEMORY_MIN_SIZE" min:"1048576"`
+	DiskColumnarTablespaceMemoryMaxSize         int64 `conf:"DISK_COLUMNAR_TABLESPACE_MEMORY_MAX_SIZE" min:"268435456"`
+	DiskColumnarTablespaceMemoryExtSize         int64 `conf:"DISK_COLUMNAR_TABLESPACE_MEMORY_EXT_SIZE" min:"1048576"`
+	DiskColumnarTablespaceMemorySlowdownHighPct int64 `conf:"DISK_COLUMNAR_TABLESPACE_MEMORY_SLOWDOWN_HIGH_LIMIT_PCT" min:"0" max:"100"`
+	DiskColumnarTablespaceMemorySlowdownMsec    int64 `conf:"DISK_COLUMNAR_TABLESPACE_MEMORY_SLOWDOWN_MSEC" min:"0" max:"4294967295"`
+	DiskColumnarTablespaceDWFileIntSize         int64 `conf:"DISK_COLUMNAR_TABLESPACE_DWFILE_INT_SIZE" min:"1048576" max:"4294967295"`
+	DiskColumnarTablespaceDWFileExtSize         int64 `conf:"DISK_COLUMNAR_TABLESPACE_DWFILE_EXT_SIZE" min:"1048576" max:"4294967295"`
+	DiskColumnarIndexShutdownBuildFinish        int64 `conf:"DISK_COLUMNAR_INDEX_SHUTDOWN_BUILD_FINISH" min:"0" max:"1"`
+	DiskColumnarPageCacheMaxSize                int64 `conf:"DISK_COLUMNAR_PAGE_CACHE_MAX_SIZE" min:"0"`
+	DiskColumnarTableTimeInversionMode          int64 `conf:"DISK_COLUMNAR_TABLE_TIME_INVERSION_MODE" min:"0" max:"1"`
+	DiskTablespaceDirectIOWrite                 int64 `conf:"DISK_TABLESPACE_DIRECT_IO_WRITE" min:"0" max:"1"`
+	DiskTablespaceDirectIORead                  int64 `conf:"DISK_TABLESPACE_DIRECT_IO_READ" min:"0" max:"1"`
+	DiskTablespaceSynchronous                   int64 `conf:"DISK_TABLESPACE_SYNCHRONOUS" min:"0" max:"3"`
+	IndexBuildThreadCount                       int64 `conf:"INDEX_BUILD_THREAD_COUNT" min:"0" max:"4294967295"`
+	IndexLevelPartitionBuildMemoryHighLimitPct  int64 `conf:"INDEX_LEVEL_PARTITION_BUILD_MEMORY_HIGH_LIMIT_PCT" min:"0" max:"100"`
+	ProcessMaxSize                              int64 `conf:"PROCESS_MAX_SIZE" min:"1073741824"`
+	MaxQpxMem                                   int64 `conf:"MAX_QPX_MEM" min:"1048576"`
+	VolatileTablespaceMemoryMaxSize             int64 `conf:"VOLATILE_TABLESPACE_MEMORY_MAX_SIZE" min:"0"`
+	TagdataAutoMetaInsert                       int64 `conf:"TAGDATA_AUTO_META_INSERT" min:"0" max:"2"`
+	SessionIdleTimeoutSec                       int64 `conf:"SESSION_IDLE_TIMEOUT_SEC" min:"0"`
+	GrantRemoteAccess                           int64 `conf:"GRANT_REMOTE_ACCESS" min:"0" max:"1"`
+	RollupFetchCountLimit                       int64 `conf:"ROLLUP_FETCH_COUNT_LIMIT" min:"0" max:"4294967295"`
+	HandleLimit                                 int64 `conf:"HANDLE_LIMIT" min:"1"`
+	HttpPortNo                                  int64 `conf:"HTTP_PORT_NO" min:"0" max:"65535"`
+	HttpEnable                                  int64 `conf:"HTTP_ENABLE" min:"0" max:"1"`
+
+	// Extra keeps the other properties in the order of appearance.
+	Extra []ConfigProperty
+}
+
+type ConfigProperty struct {
+	Name  string
+	Value string
+}
+
+// DefaultConfig returns the config that has the same values
+// with the machbase.conf which is distributed with the engine.
+func DefaultConfig() *Config {
+	return &Config{
+		PortNo:            5656,
+		BindIPAddress:     "127.0.0.1",
+		DbsPath:           "?/dbs",
+		TraceLogfileSize:  10 * sizeMB,
+		TraceLogfileCount: 1000,
+		TraceLogfilePath:  "?/trc",
+		TraceLogLevel:     277,
+
+		DurationGap: 0,
+		CPUParallel: 1,
+
+		DiskColumnarTableCheckpointIntervalSec:      120,
+		DiskColumnarIndexCheckpointIntervalSec:      120,
+		DiskColumnarTableColumnPartFlushMode:        0,
+		DiskColumnarTableColumnPartIOIntervalMinSec: 3,
+		DiskIOThreadCount:                           3,
+		DiskColumnarTablespaceMemoryMinSize:         100 * sizeMB,
+		DiskColumnarTablespaceMemoryMaxSize:         256 * sizeMB,
+		DiskColumnarTablespaceMemoryExtSize:         2 * sizeMB,
+		DiskColumnarTablespaceMemorySlowdownHighPct: 80,
+		DiskColumnarTablespaceMemorySlowdownMsec:    1,
+		DiskColumnarTablespaceDWFileIntSize:         2 * sizeMB,
+		DiskColumnarTablespaceDWFileExtSize:         1 * sizeMB,
+		DiskColumnarIndexShutdownBuildFinish:        0,
+		DiskColumnarPageCacheMaxSize:                128 * sizeMB,
+		DiskColumnarTableTimeInversionMode:          1,
+		DiskTablespaceDirectIOWrite:                 1,
+		DiskTablespaceDirectIORead:                  0,
+		DiskTablespaceSynchronous:                   1,
+		IndexBuildThreadCount:                       3,
+		IndexLevelPartitionBuildMemoryHighLimitPct:  70,
+		ProcessMaxSize:                              4 * sizeGB,
+		MaxQpxMem:                                   256 * sizeMB,
+		VolatileTablespaceMemoryMaxSize:             512 * sizeMB,
+		TagdataAutoMetaInsert:                       2,
+		SessionIdleTimeoutSec:                       0,
+		GrantRemoteAccess:                           1,
+		RollupFetchCountLimit:                       10000,
+		HandleLimit:                                 1024,
+		HttpPortNo:                                  5657,
+		HttpEnable:                                  0,
+	}
+}
+
+// LoadConfig reads the config file at path.
+func LoadConfig(path string) (*Config, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ParseConfig(f)
+}
+
+// ParseConfig reads 'KEY = VALUE' lines, '#' starts a comment.
+// The properties that do not appear keep the values of DefaultConfig().
+func ParseConfig(r io.Reader) (*Config, error) {
+	ret := DefaultConfig()
+	scanner := bufio.NewScanner(r)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := scanner.Text()
+		if idx := strings.IndexByte(line, '#'); idx >= 0 {
+			line = line[:idx]
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(line, "=")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return nil, ErrConfigSyntax(lineNo, scanner.Text())
+		}
+		if err := ret.Set(name, strings.TrimSpace(value)); err != nil {
+			return nil, ErrConfigAtLine(lineNo, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+// configField returns the field of the property name, or invalid value if it is not a field.
+func (c *Config) configField(name string) (reflect.Value, reflect.StructField) {
+	rv := reflect.ValueOf(c).Elem()
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		sf := rt.Field(i)
+		if tag, ok := sf.Tag.Lookup("conf"); ok && tag == name {
+			return rv.Field(i), sf
+		}
+	}
+	return reflect.Value{}, reflect.StructField{}
+}
+
+// Get returns the value of the property in the text form.
+func (c *Config) Get(name string) (string, bool) {
+	name = strings.ToUpper(name)
+	if fv, _ := c.configField(name); fv.IsValid() {
+		if fv.Kind() == reflect.String {
+			return fv.String(), true
+		}
+		return strconv.FormatInt(fv.Int(), 10), true
+	}
+	for _, p := range c.Extra {
+		if p.Name == name {
+			return p.Value, true
+		}
+	}
+	return "", false
+}
+
+// Set updates the property from the text form.
+// The range is not checked until Validate() is called.
+func (c *Config) Set(name string, value string) error {
+	name = strings.ToUpper(name)
+	if fv, _ := c.configField(name); fv.IsValid() {
+		if fv.Kind() == reflect.String {
+			fv.SetString(value)
+			return nil
+		}
+		v, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return ErrConfigInvalidValue(name, value)
+		}
+		fv.SetInt(v)
+		return nil
+	}
+	for i, p := range c.Extra {
+		if p.Name == name {
+			c.Extra[i].Value = value
+			return nil
+		}
+	}
+	c.Extra = append(c.Extra, ConfigProperty{Name: name, Value: value})
+	return nil
+}
+
+// Render writes the config in the format of machbase.conf.
+func (c *Config) Render(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	for _, p := range c.Properties() {
+		fmt.Fprintf(bw, "%s = %s\n", p.Name, p.Value)
+	}
+	return bw.Flush()
+}
+
+// Properties returns all properties of the config in the order of Render().
+func (c *Config) Properties() []ConfigProperty {
+	rv := reflect.ValueOf(c).Elem()
+	rt := rv.Type()
+	ret := make([]ConfigProperty, 0, rt.NumField()+len(c.Extra))
+	for i := 0; i < rt.NumField(); i++ {
+		name, ok := rt.Field(i).Tag.Lookup("conf")
+		if !ok {
+			continue
+		}
+		value, _ := c.Get(name)
+		ret = append(ret, ConfigProperty{Name: name, Value: value})
+	}
+	return append(ret, c.Extra...)
+}
+
+func (c *Config) String() string {
+	buf := &bytes.Buffer{}
+	c.Render(buf)
+	return buf.String()
+}
+
+// WriteFile renders the config into the file at path.
+func (c *Config) WriteFile(path string) error {
+	buf := &bytes.Buffer{}
+	if err := c.Render(buf); err != nil {
+		return err
+	}
+	return os.WriteFile(path, buf.Bytes(), 0644)
+}
+
+// Validate checks the range of the properties,
+// it returns all the violations joined.
+func (c *Config) Validate() error {
+	var errs []error
+	rv := reflect.ValueOf(c).Elem()
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		sf := rt.Field(i)
+		name, ok := sf.Tag.Lookup("conf")
+		if !ok || rv.Field(i).Kind() != reflect.Int64 {
+			continue
+		}
+		v := rv.Field(i).Int()
+		if min, ok := sf.Tag.Lookup("min"); ok {
+			if n, _ := strconv.ParseInt(min, 10, 64); v < n {
+				errs = append(errs, ErrConfigOutOfRange(name, v, sf.Tag.Get("min"), sf.Tag.Get("max")))
+				continue
+			}
+		}
+		if max, ok := sf.Tag.Lookup("max"); ok {
+			if n, _ := strconv.ParseInt(max, 10, 64); v > n {
+				errs = append(errs, ErrConfigOutOfRange(name, v, sf.Tag.Get("min"), sf.Tag.Get("max")))
+			}
+		}
+	}
+	if net.ParseIP(c.BindIPAddress) == nil {
+		errs = append(errs, ErrConfigInvalidValue("BIND_IP_ADDRESS", c.BindIPAddress))
+	}
+	if c.DbsPath == "" {
+		errs = append(errs, ErrConfigInvalidValue("DBS_PATH", c.DbsPath))
+	}
+	if c.TraceLogfilePath == "" {
+		errs = append(errs, ErrConfigInvalidValue("TRACE_LOGFILE_PATH", c.TraceLogfilePath))
+	}
+	return errors.Join(errs...)
+}
+
+// DbsDir returns DBS_PATH that '?' is replaced with homeDir.
+func (c *Config) DbsDir(homeDir string) string {
+	return ExpandHome(c.DbsPath, homeDir)
+}
+
+// TraceLogDir returns TRACE_LOGFILE_PATH that '?' is replaced with homeDir.
+func (c *Config) TraceLogDir(homeDir string) string {
+	return ExpandHome(c.TraceLogfilePath, homeDir)
+}
+
+// ExpandHome replaces the leading '?' of the path value with homeDir.
+func ExpandHome(value string, homeDir string) string {
+	if !strings.HasPrefix(value, ConfigHomeToken) {
+		return value
+	}
+	return filepath.Join(homeDir, filepath.FromSlash(strings.TrimPrefix(value, ConfigHomeToken)))
+}
@@ -0,0 +1,55 @@
+package mach_test
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	mach "github.com/machbase/neo-engine/v8"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigParse(t *testing.T) {
+	conf, err := mach.ParseConfig(bytes.NewReader(machbase_conf))
+	require.NoError(t, err)
+	require.NoError(t, conf.Validate())
+
+	require.Equal(t, int64(5656), conf.PortNo)
+	require.Equal(t, "127.0.0.1", conf.BindIPAddress)
+	require.Equal(t, "?/dbs", conf.DbsPath)
+	require.Equal(t, int64(10485760), conf.TraceLogfileSize)
+	require.Equal(t, int64(277), conf.TraceLogLevel)
+	require.Equal(t, int64(4294967296), conf.ProcessMaxSize)
+	require.Equal(t, filepath.Join("/home", "machbase", "dbs"), conf.DbsDir(filepath.Join("/home", "machbase")))
+
+	// properties without fields are kept
+	v, ok := conf.Get("TAG_PARTITION_COUNT")
+	require.True(t, ok)
+	require.Equal(t, "1", v)
+
+	// render and parse again
+	buf := &bytes.Buffer{}
+	require.NoError(t, conf.Render(buf))
+	reload, err := mach.ParseConfig(buf)
+	require.NoError(t, err)
+	require.Equal(t, conf, reload)
+}
+
+func TestConfigInvalid(t *testing.T) {
+	_, err := mach.ParseConfig(strings.NewReader("# comment\nPORT_NO 5656\n"))
+	require.ErrorContains(t, err, "line 2")
+
+	_, err = mach.ParseConfig(strings.NewReader("PORT_NO = abc\n"))
+	require.ErrorContains(t, err, "PORT_NO")
+
+	conf := mach.DefaultConfig()
+	require.NoError(t, conf.Validate())
+	conf.PortNo = 70000
+	conf.DiskColumnarTablespaceMemorySlowdownHighPct = 101
+	conf.BindIPAddress = "localhost"
+	err = conf.Validate()
+	require.ErrorContains(t, err, "PORT_NO")
+	require.ErrorContains(t, err, "DISK_COLUMNAR_TABLESPACE_MEMORY_SLOWDOWN_HIGH_LIMIT_PCT")
+	require.ErrorContains(t, err, "BIND_IP_ADDRESS")
+}
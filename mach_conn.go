@@ -0,0 +1,303 @@
+package mach
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+	"unsafe"
+)
+
+// Conn is an engine connection that is tracked by its Env.
+type Conn struct {
+	env       *Env
+	handle    unsafe.Pointer
+	sessionID uint64
+	mu        sync.Mutex
+	stmts     map[*Stmt]struct{}
+	stmtCache *stmtLRU[*Stmt]
+	closing   bool // Close() is waiting for the running statements
+	closed    bool
+}
+
+// Connect opens a connection with the user's password.
+func (env *Env) Connect(username string, password string) (*Conn, error) {
+	return env.connect(func(handle unsafe.Pointer, conn *unsafe.Pointer) error {
+		return EngConnect(handle, username, password, conn)
+	})
+}
+
+// ConnectTrust opens a connection without password.
+func (env *Env) ConnectTrust(username string) (*Conn, error) {
+	return env.connect(func(handle unsafe.Pointer, conn *unsafe.Pointer) error {
+		return EngConnectTrust(handle, username, conn)
+	})
+}
+
+func (env *Env) connect(fn func(unsafe.Pointer, *unsafe.Pointer) error) (*Conn, error) {
+	env.mu.Lock()
+	defer env.mu.Unlock()
+	if err := env.check("Connect", EnvStateStarted); err != nil {
+		return nil, err
+	}
+	if env.draining {
+		return nil, ErrEnvDraining(env.homeDir)
+	}
+	ret := &Conn{env: env, stmts: map[*Stmt]struct{}{}, stmtCache: newStmtLRU[*Stmt](DefaultStmtCacheSize)}
+	if err := fn(env.handle, &ret.handle); err != nil {
+		return nil, err
+	}
+	ret.sessionID, _ = EngSessionID(ret.handle)
+	if env.conns == nil {
+		env.conns = map[*Conn]struct{}{}
+	}
+	env.conns[ret] = struct{}{}
+	return ret, nil
+}
+
+// Conns returns the connections that are not closed yet.
+func (env *Env) Conns() []*Conn {
+	env.mu.Lock()
+	defer env.mu.Unlock()
+	ret := make([]*Conn, 0, len(env.conns))
+	for c := range env.conns {
+		ret = append(ret, c)
+	}
+	return ret
+}
+
+func (conn *Conn) Handle() unsafe.Pointer {
+	return conn.handle
+}
+
+func (conn *Conn) SessionID() uint64 {
+	return conn.sessionID
+}
+
+// Cancel cancels the statement that is running on the connection.
+func (conn *Conn) Cancel() error {
+	conn.mu.Lock()
+	defer conn.mu.Unlock()
+	if conn.closed {
+		return ErrConnClosed(conn.sessionID)
+	}
+	return EngCancel(conn.handle)
+}
+
+// Close frees the statements of the connection including the cached ones, and disconnects.
+// The running statements are canceled and Close() waits for them up to ShutdownCancelGrace,
+// if they do not return, it returns error and the connection is left open.
+// It is safe to call Close() more than once, and after the env is closed.
+func (conn *Conn) Close() error {
+	err := conn.close()
+	if conn.isClosed() {
+		conn.env.mu.Lock()
+		delete(conn.env.conns, conn)
+		conn.env.mu.Unlock()
+	}
+	return err
+}
+
+// close is Close() without untracking the connection from the env.
+func (conn *Conn) close() error {
+	conn.mu.Lock()
+	if conn.closed {
+		conn.mu.Unlock()
+		return nil
+	}
+	conn.closing = true
+	conn.mu.Unlock()
+	if !conn.waitInactive(ShutdownCancelGrace) {
+		// the connection is left open and usable
+		conn.mu.Lock()
+		conn.closing = false
+		conn.mu.Unlock()
+		return ErrConnBusy(conn.sessionID)
+	}
+
+	conn.mu.Lock()
+	defer conn.mu.Unlock()
+	if conn.closed {
+		return nil
+	}
+	conn.stmtCache.drain()
+	for stmt := range conn.stmts {
+		stmt.free()
+	}
+	conn.stmts = nil
+	conn.closed = true
+	return EngDisconnect(conn.handle)
+}
+
+func (conn *Conn) isClosed() bool {
+	conn.mu.Lock()
+	defer conn.mu.Unlock()
+	return conn.closed
+}
+
+// waitInactive cancels the running statements, and returns true when they return,
+// false if they are still running after timeout.
+func (conn *Conn) waitInactive(timeout time.Duration) bool {
+	if conn.active() == 0 {
+		return true
+	}
+	conn.Cancel()
+	deadline := time.Now().Add(timeout)
+	for conn.active() > 0 {
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return true
+}
+
+// active returns the number of statements that are executing or fetching.
+func (conn *Conn) active() int {
+	conn.mu.Lock()
+	defer conn.mu.Unlock()
+	ret := 0
+	for stmt := range conn.stmts {
+		ret += int(stmt.active.Load())
+	}
+	return ret
+}
+
+// Stmt is a statement of Conn.
+type Stmt struct {
+	conn        *Conn
+	handle      unsafe.Pointer
+	active      atomic.Int32
+	appendTable string
+	cacheKey    string // SQL text if it is from Conn.Prepared()
+	closed      bool
+}
+
+// NewStmt allocates a new statement on the connection.
+func (conn *Conn) NewStmt() (*Stmt, error) {
+	conn.mu.Lock()
+	defer conn.mu.Unlock()
+	if conn.closed || conn.closing {
+		return nil, ErrConnClosed(conn.sessionID)
+	}
+	ret := &Stmt{conn: conn}
+	if err := EngAllocStmt(conn.handle, &ret.handle); err != nil {
+		return nil, err
+	}
+	conn.stmts[ret] = struct{}{}
+	return ret, nil
+}
+
+func (stmt *Stmt) Handle() unsafe.Pointer {
+	return stmt.handle
+}
+
+func (stmt *Stmt) Conn() *Conn {
+	return stmt.conn
+}
+
+// begin marks the statement is running, end() should be called when it is done.
+func (stmt *Stmt) begin() error {
+	stmt.conn.mu.Lock()
+	defer stmt.conn.mu.Unlock()
+	if stmt.closed || stmt.conn.closing {
+		return ErrStmtClosed(stmt.conn.sessionID)
+	}
+	stmt.active.Add(1)
+	return nil
+}
+
+func (stmt *Stmt) end() {
+	stmt.active.Add(-1)
+}
+
+func (stmt *Stmt) Prepare(sqlText string) error {
+	if err := stmt.begin(); err != nil {
+		return err
+	}
+	defer stmt.end()
+	return EngPrepare(stmt.handle, sqlText)
+}
+
+func (stmt *Stmt) Execute() error {
+	if err := stmt.begin(); err != nil {
+		return err
+	}
+	defer stmt.end()
+	return EngExecute(stmt.handle)
+}
+
+func (stmt *Stmt) ExecuteClean() error {
+	if err := stmt.begin(); err != nil {
+		return err
+	}
+	defer stmt.end()
+	return EngExecuteClean(stmt.handle)
+}
+
+func (stmt *Stmt) DirectExecute(sqlText string) error {
+	if err := stmt.begin(); err != nil {
+		return err
+	}
+	defer stmt.end()
+	return EngDirectExecute(stmt.handle, sqlText)
+}
+
+// Fetch returns true if a record exists.
+func (stmt *Stmt) Fetch() (bool, error) {
+	if err := stmt.begin(); err != nil {
+		return false, err
+	}
+	defer stmt.end()
+	return EngFetch(stmt.handle)
+}
+
+func (stmt *Stmt) AppendOpen(tableName string) error {
+	if err := stmt.begin(); err != nil {
+		return err
+	}
+	defer stmt.end()
+	if err := EngAppendOpen(stmt.handle, tableName); err != nil {
+		return err
+	}
+	stmt.conn.mu.Lock()
+	stmt.appendTable = tableName
+	stmt.conn.mu.Unlock()
+	return nil
+}
+
+// AppendClose returns the success and failure count of the appender.
+func (stmt *Stmt) AppendClose() (int64, int64, error) {
+	stmt.conn.mu.Lock()
+	defer stmt.conn.mu.Unlock()
+	if stmt.closed {
+		return 0, 0, ErrStmtClosed(stmt.conn.sessionID)
+	}
+	stmt.appendTable = ""
+	return EngAppendClose(stmt.handle)
+}
+
+// Close frees the statement, an open appender is closed first.
+// It returns error if the statement is executing or fetching in another goroutine,
+// the statement is left open then.
+func (stmt *Stmt) Close() error {
+	stmt.conn.mu.Lock()
+	defer stmt.conn.mu.Unlock()
+	if stmt.closed {
+		return nil
+	}
+	if stmt.active.Load() > 0 {
+		return ErrConnBusy(stmt.conn.sessionID)
+	}
+	delete(stmt.conn.stmts, stmt)
+	return stmt.free()
+}
+
+// free releases the native statement, the caller should hold conn.mu.
+func (stmt *Stmt) free() error {
+	stmt.closed = true
+	if stmt.appendTable != "" {
+		stmt.appendTable = ""
+		EngAppendClose(stmt.handle)
+	}
+	return EngFreeStmt(stmt.handle)
+}
@@ -0,0 +1,56 @@
+package mach_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestConnStmt(t *testing.T) {
+	conn, err := global.Env.ConnectTrust("sys")
+	require.NoError(t, err)
+	require.Contains(t, global.Env.Conns(), conn)
+
+	stmt, err := conn.NewStmt()
+	require.NoError(t, err)
+	require.NoError(t, stmt.DirectExecute(`select count(*) from m$sys_users`))
+	next, err := stmt.Fetch()
+	require.NoError(t, err)
+	require.True(t, next)
+	require.NoError(t, stmt.Close())
+	require.NoError(t, stmt.Close())
+	require.Error(t, stmt.Execute())
+
+	// statements left open are freed by Close()
+	_, err = conn.NewStmt()
+	require.NoError(t, err)
+	require.NoError(t, conn.Close())
+	require.NoError(t, conn.Close())
+	require.NotContains(t, global.Env.Conns(), conn)
+
+	_, err = conn.NewStmt()
+	require.Error(t, err)
+}
+
+// longQuery runs long enough to be canceled while it is running.
+const longQuery = `select count(*) from m$sys_columns a, m$sys_columns b, m$sys_columns c, m$sys_columns d`
+
+func TestStmtCloseRunning(t *testing.T) {
+	conn, err := global.Env.ConnectTrust("sys")
+	require.NoError(t, err)
+	defer conn.Close()
+	stmt, err := conn.NewStmt()
+	require.NoError(t, err)
+
+	done := make(chan error, 1)
+	go func() {
+		done <- stmt.DirectExecute(longQuery)
+	}()
+	time.Sleep(200 * time.Millisecond)
+	// the running statement is not freed
+	require.Error(t, stmt.Close())
+	require.NoError(t, conn.Cancel())
+	require.Error(t, <-done)
+	require.NoError(t, stmt.Close())
+}
@@ -0,0 +1,117 @@
+package mach
+
+import (
+	"context"
+	"unsafe"
+)
+
+// runContext runs op and calls cancel when ctx ends before op returns.
+// If ctx ended and op failed, the returned error wraps both ctx.Err() and the error of op,
+// op that succeeded is not reported as canceled even if ctx ended right after it.
+// cancel is never called after runContext returns.
+func runContext(ctx context.Context, cancel func() error, op func() error) error {
+	if err := ctx.Err(); err != nil {
+		return ErrDatabaseCanceled(err, nil)
+	}
+	canceled := make(chan struct{})
+	stop := context.AfterFunc(ctx, func() {
+		defer close(canceled)
+		cancel()
+	})
+	err := op()
+	if !stop() {
+		<-canceled
+		if err != nil {
+			return ErrDatabaseCanceled(ctx.Err(), err)
+		}
+	}
+	return err
+}
+
+// ExecContext is DirectExecute() that is canceled when ctx ends.
+func (stmt *Stmt) ExecContext(ctx context.Context, sqlText string) error {
+	return runContext(ctx, stmt.conn.Cancel, func() error {
+		return stmt.DirectExecute(sqlText)
+	})
+}
+
+// QueryContext prepares and executes sqlText, then the result is fetched by FetchContext().
+// If ctx ends, the running statement is canceled and cleaned up for reuse.
+func (stmt *Stmt) QueryContext(ctx context.Context, sqlText string) error {
+	err := runContext(ctx, stmt.conn.Cancel, func() error {
+		if err := stmt.Prepare(sqlText); err != nil {
+			return err
+		}
+		return stmt.Execute()
+	})
+	if err != nil {
+		stmt.ExecuteClean()
+	}
+	return err
+}
+
+// FetchContext is Fetch() that is canceled when ctx ends.
+// If ctx ends, the statement is cleaned up for reuse,
+// the other errors are left to the caller that cleans up the statement.
+func (stmt *Stmt) FetchContext(ctx context.Context) (bool, error) {
+	var next bool
+	err := runContext(ctx, stmt.conn.Cancel, func() error {
+		var err error
+		next, err = stmt.Fetch()
+		return err
+	})
+	if err != nil {
+		if ctx.Err() != nil {
+			stmt.ExecuteClean()
+		}
+		return false, err
+	}
+	return next, nil
+}
+
+// CliExecContext is CliExecDirect() that is canceled by CliCancel() when ctx ends.
+func CliExecContext(ctx context.Context, stmt unsafe.Pointer, query string) error {
+	return runContext(ctx, cliCancelFunc(stmt), func() error {
+		return CliExecDirect(stmt, query)
+	})
+}
+
+// CliQueryContext prepares and executes query, then the result is fetched by CliFetchContext().
+// If ctx ends, the running statement is canceled and cleaned up for reuse.
+func CliQueryContext(ctx context.Context, stmt unsafe.Pointer, query string) error {
+	err := runContext(ctx, cliCancelFunc(stmt), func() error {
+		if err := CliPrepare(stmt, query); err != nil {
+			return err
+		}
+		return CliExecute(stmt)
+	})
+	if err != nil {
+		CliExecuteClean(stmt)
+	}
+	return err
+}
+
+// CliFetchContext is CliFetch() that is canceled when ctx ends, it returns true if it reaches the end.
+// If ctx ends, the statement is cleaned up for reuse,
+// the other errors are left to the caller that cleans up the statement.
+func CliFetchContext(ctx context.Context, stmt unsafe.Pointer) (bool, error) {
+	var end bool
+	err := runContext(ctx, cliCancelFunc(stmt), func() error {
+		var err error
+		end, err = CliFetch(stmt)
+		return err
+	})
+	if err != nil {
+		if ctx.Err() != nil {
+			CliExecuteClean(stmt)
+		}
+		return true, err
+	}
+	return end, nil
+}
+
+func cliCancelFunc(stmt unsafe.Pointer) func() error {
+	return func() error {
+		return CliCancel(stmt)
+	}
+}
@@ -0,0 +1,106 @@
+package mach_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+	"unsafe"
+
+	mach "github.com/machbase/neo-engine/v8"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStmtContext(t *testing.T) {
+	conn, err := global.Env.ConnectTrust("sys")
+	require.NoError(t, err)
+	defer conn.Close()
+	stmt, err := conn.NewStmt()
+	require.NoError(t, err)
+	defer stmt.Close()
+
+	canceledCtx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = stmt.QueryContext(canceledCtx, `select count(*) from m$sys_users`)
+	require.ErrorIs(t, err, context.Canceled)
+
+	// the statement is reusable after the cancellation
+	ctx := context.Background()
+	require.NoError(t, stmt.QueryContext(ctx, `select count(*) from m$sys_users`))
+	next, err := stmt.FetchContext(ctx)
+	require.NoError(t, err)
+	require.True(t, next)
+	_, err = stmt.FetchContext(canceledCtx)
+	require.ErrorIs(t, err, context.Canceled)
+	require.NoError(t, stmt.ExecContext(ctx, `select count(*) from m$sys_users`))
+}
+
+func TestCliContext(t *testing.T) {
+	var conn unsafe.Pointer
+	err := mach.CliConnect(global.CliEnv, fmt.Sprintf("SERVER=127.0.0.1;UID=SYS;PWD=MANAGER;CONNTYPE=1;PORT_NO=%d", machPort), &conn)
+	require.NoError(t, err)
+	defer mach.CliDisconnect(conn)
+	var stmt unsafe.Pointer
+	require.NoError(t, mach.CliAllocStmt(conn, &stmt))
+	defer mach.CliFreeStmt(stmt)
+
+	canceledCtx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = mach.CliQueryContext(canceledCtx, stmt, `select count(*) from m$sys_users`)
+	require.ErrorIs(t, err, context.Canceled)
+
+	ctx := context.Background()
+	require.NoError(t, mach.CliQueryContext(ctx, stmt, `select count(*) from m$sys_users`))
+	end, err := mach.CliFetchContext(ctx, stmt)
+	require.NoError(t, err)
+	require.False(t, end)
+	require.NoError(t, mach.CliExecuteClean(stmt))
+	require.NoError(t, mach.CliExecContext(ctx, stmt, `select count(*) from m$sys_users`))
+}
+
+func TestContextCancelRunning(t *testing.T) {
+	conn, err := global.Env.ConnectTrust("sys")
+	require.NoError(t, err)
+	defer conn.Close()
+	stmt, err := conn.NewStmt()
+	require.NoError(t, err)
+	defer stmt.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(200*time.Millisecond, cancel)
+	// the query may run long in the execution or in the fetch
+	err = stmt.QueryContext(ctx, longQuery)
+	if err == nil {
+		_, err = stmt.FetchContext(ctx)
+	}
+	require.ErrorIs(t, err, context.Canceled)
+	// the error of the engine is wrapped too
+	require.Len(t, err.(interface{ Unwrap() []error }).Unwrap(), 2)
+	require.Contains(t, err.Error(), "MACH-ERR")
+
+	// the statement is reusable after the cancellation
+	require.NoError(t, stmt.ExecContext(context.Background(), `select count(*) from m$sys_users`))
+	require.NoError(t, stmt.ExecuteClean())
+
+	var cliConn unsafe.Pointer
+	err = mach.CliConnect(global.CliEnv, fmt.Sprintf("SERVER=127.0.0.1;UID=SYS;PWD=MANAGER;CONNTYPE=1;PORT_NO=%d", machPort), &cliConn)
+	require.NoError(t, err)
+	defer mach.CliDisconnect(cliConn)
+	var cliStmt unsafe.Pointer
+	require.NoError(t, mach.CliAllocStmt(cliConn, &cliStmt))
+	defer mach.CliFreeStmt(cliStmt)
+
+	ctx, cancel = context.WithCancel(context.Background())
+	time.AfterFunc(200*time.Millisecond, cancel)
+	err = mach.CliQueryContext(ctx, cliStmt, longQuery)
+	if err == nil {
+		_, err = mach.CliFetchContext(ctx, cliStmt)
+	}
+	require.ErrorIs(t, err, context.Canceled)
+	var cliErr *mach.CliErr
+	require.True(t, errors.As(err, &cliErr))
+
+	require.NoError(t, mach.CliExecContext(context.Background(), cliStmt, `select count(*) from m$sys_users`))
+	require.NoError(t, mach.CliExecuteClean(cliStmt))
+}
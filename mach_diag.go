@@ -0,0 +1,138 @@
+package mach
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/machbase/neo-engine/v8/native"
+)
+
+// DiagnosticsVersionQuery is the query that Diagnostics() takes the server version from.
+var DiagnosticsVersionQuery = "select * from v$version"
+
+// Diagnostics is a snapshot of the env for support, it can be marshalled to JSON.
+type Diagnostics struct {
+	Time          time.Time         `json:"time"`
+	LinkInfo      string            `json:"linkInfo"`
+	Version       string            `json:"version"`
+	GitHash       string            `json:"gitHash"`
+	ServerVersion map[string]string `json:"serverVersion,omitempty"`
+	// ServerVersionErr is the error of DiagnosticsVersionQuery if it failed.
+	ServerVersionErr string `json:"serverVersionErr,omitempty"`
+
+	HomeDir   string    `json:"homeDir"`
+	Port      int       `json:"port"`
+	Flag      InitFlag  `json:"flag"`
+	State     string    `json:"state"`
+	StartedAt time.Time `json:"startedAt"`
+	Uptime    float64   `json:"uptimeSec"`
+
+	// EngConnectionCount() of the engine, it includes the connections that are not made by the Env.
+	EngConnections int         `json:"engConnections"`
+	EnvConnections int         `json:"envConnections"`
+	CliEnvs        int         `json:"cliEnvs"`
+	AllocStat      AllocStat   `json:"allocStat"`
+	Pools          []PoolStats `json:"pools,omitempty"`
+
+	Config  map[string]string `json:"config,omitempty"`
+	DbsPath string            `json:"dbsPath,omitempty"`
+	DbsSize int64             `json:"dbsSize"`
+}
+
+// Diagnostics gathers the snapshot of the started env.
+// A failure of the server version query is recorded in ServerVersionErr
+// instead of failing the whole snapshot.
+func (env *Env) Diagnostics(ctx context.Context) (*Diagnostics, error) {
+	env.mu.Lock()
+	if err := env.check("Diagnostics", EnvStateStarted); err != nil {
+		env.mu.Unlock()
+		return nil, err
+	}
+	now := time.Now()
+	ret := &Diagnostics{
+		Time:           now,
+		LinkInfo:       LinkInfo(),
+		Version:        native.Version,
+		GitHash:        native.GitHash,
+		HomeDir:        env.homeDir,
+		Port:           env.port,
+		Flag:           env.flag,
+		State:          env.state.String(),
+		StartedAt:      env.startedAt,
+		Uptime:         now.Sub(env.startedAt).Seconds(),
+		EngConnections: EngConnectionCount(env.handle),
+		EnvConnections: len(env.conns),
+		AllocStat:      Stat(),
+	}
+	env.mu.Unlock()
+
+	ret.CliEnvs = CliEnvCount()
+	for _, pool := range env.Pools() {
+		ret.Pools = append(ret.Pools, pool.Stats())
+	}
+	if env.config != nil {
+		ret.Config = map[string]string{}
+		for _, p := range env.config.Properties() {
+			ret.Config[p.Name] = p.Value
+		}
+		ret.DbsPath = env.config.DbsDir(env.homeDir)
+		ret.DbsSize = dirSize(ret.DbsPath)
+	}
+	if ver, err := env.serverVersion(ctx); err != nil {
+		ret.ServerVersionErr = err.Error()
+	} else {
+		ret.ServerVersion = ver
+	}
+	return ret, nil
+}
+
+// serverVersion returns the first row of DiagnosticsVersionQuery by the column names.
+func (env *Env) serverVersion(ctx context.Context) (map[string]string, error) {
+	conn, err := env.ConnectTrust("sys")
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	stmt, err := conn.NewStmt()
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	ret := map[string]string{}
+	err = runContext(ctx, conn.Cancel, func() error {
+		if err := stmt.DirectExecute(DiagnosticsVersionQuery); err != nil {
+			return err
+		}
+		if exists, err := stmt.Fetch(); err != nil {
+			return err
+		} else if !exists {
+			return ErrDatabaseNoRows(DiagnosticsVersionQuery)
+		}
+		count, err := EngColumnCount(stmt.handle)
+		if err != nil {
+			return err
+		}
+		for i := 0; i < count; i++ {
+			name, err := EngColumnName(stmt.handle, i)
+			if err != nil {
+				return err
+			}
+			value, valid, err := engColumnValue(stmt.handle, i)
+			if err != nil {
+				return err
+			}
+			if valid {
+				ret[name] = fmt.Sprint(value)
+			} else {
+				ret[name] = ""
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
@@ -0,0 +1,29 @@
+package mach_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	mach "github.com/machbase/neo-engine/v8"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiagnostics(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	diag, err := global.Env.Diagnostics(ctx)
+	require.NoError(t, err)
+	require.Equal(t, mach.LinkInfo(), diag.LinkInfo)
+	require.Equal(t, "started", diag.State)
+	require.Empty(t, diag.ServerVersionErr)
+	require.NotEmpty(t, diag.ServerVersion)
+	require.Greater(t, diag.Uptime, 0.0)
+	require.Equal(t, "5656", diag.Config["PORT_NO"])
+	require.Greater(t, diag.DbsSize, int64(0))
+
+	b, err := json.Marshal(diag)
+	require.NoError(t, err)
+	require.Contains(t, string(b), `"allocStat"`)
+}
@@ -0,0 +1,429 @@
+package mach
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"math"
+	"net"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// EmbedDriverName is the database/sql driver name of the engine in the process.
+//
+// The DSN is `HOME=<home dir>;UID=<user>;PWD=<password>`, every part is optional.
+// HOME can be omitted if only one Env is alive in the process,
+// UID is "sys" by default and the connection is made without password if PWD is empty.
+//
+//	db, err := sql.Open(mach.EmbedDriverName, "UID=sys")
+const EmbedDriverName = "machbase-embed"
+
+func init() {
+	sql.Register(EmbedDriverName, &EmbedDriver{})
+}
+
+// EmbedDriver is the database/sql driver of the engine in the process.
+type EmbedDriver struct{}
+
+var _ driver.Driver = (*EmbedDriver)(nil)
+var _ driver.DriverContext = (*EmbedDriver)(nil)
+
+func (d *EmbedDriver) Open(dsn string) (driver.Conn, error) {
+	connector, err := d.OpenConnector(dsn)
+	if err != nil {
+		return nil, err
+	}
+	return connector.Connect(context.Background())
+}
+
+func (d *EmbedDriver) OpenConnector(dsn string) (driver.Connector, error) {
+	props, err := parseDSN(dsn)
+	if err != nil {
+		return nil, err
+	}
+	env, err := lookupEnv(props["HOME"])
+	if err != nil {
+		return nil, err
+	}
+	user := props["UID"]
+	if user == "" {
+		user = "sys"
+	}
+	return &embedConnector{env: env, user: user, password: props["PWD"]}, nil
+}
+
+// parseDSN returns the properties of `KEY=VALUE;KEY=VALUE`, the keys are upper-cased.
+func parseDSN(dsn string) (map[string]string, error) {
+	ret := map[string]string{}
+	for _, part := range strings.Split(dsn, ";") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, ErrDriverInvalidDSN(part)
+		}
+		ret[strings.ToUpper(strings.TrimSpace(key))] = strings.TrimSpace(value)
+	}
+	return ret, nil
+}
+
+// lookupEnv returns the alive Env on homeDir, or the only Env of the process if homeDir is empty.
+func lookupEnv(homeDir string) (*Env, error) {
+	registry.Lock()
+	defer registry.Unlock()
+	if homeDir == "" {
+		if len(registry.envs) == 1 {
+			for _, env := range registry.envs {
+				return env, nil
+			}
+		}
+		return nil, ErrDriverEnvNotFound(homeDir)
+	}
+	absHome, err := filepath.Abs(homeDir)
+	if err != nil {
+		return nil, err
+	}
+	if env, ok := registry.envs[absHome]; ok {
+		return env, nil
+	}
+	return nil, ErrDriverEnvNotFound(absHome)
+}
+
+// Connector returns the database/sql connector of the env,
+// the connections are made without password if it is empty.
+//
+//	db := sql.OpenDB(env.Connector("sys", ""))
+func (env *Env) Connector(username string, password string) driver.Connector {
+	return &embedConnector{env: env, user: username, password: password}
+}
+
+type embedConnector struct {
+	env      *Env
+	user     string
+	password string
+}
+
+func (c *embedConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	var conn *Conn
+	var err error
+	if c.password == "" {
+		conn, err = c.env.ConnectTrust(c.user)
+	} else {
+		conn, err = c.env.Connect(c.user, c.password)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &embedConn{conn: conn}, nil
+}
+
+func (c *embedConnector) Driver() driver.Driver {
+	return &EmbedDriver{}
+}
+
+type embedConn struct {
+	conn *Conn
+}
+
+var _ driver.Conn = (*embedConn)(nil)
+var _ driver.ConnPrepareContext = (*embedConn)(nil)
+var _ driver.ExecerContext = (*embedConn)(nil)
+var _ driver.QueryerContext = (*embedConn)(nil)
+var _ driver.Pinger = (*embedConn)(nil)
+var _ driver.Validator = (*embedConn)(nil)
+var _ driver.NamedValueChecker = (*embedConn)(nil)
+
+func (c *embedConn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+// PrepareContext prepares query, `:name` and `@name` placeholders are bound by sql.Named() arguments.
+// If query has them, it is prepared at the execution when the arguments are known,
+// since they are rewritten only for sql.Named() arguments.
+func (c *embedConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	stmt, err := c.conn.NewStmt()
+	if err != nil {
+		return nil, err
+	}
+	ret := &embedStmt{stmt: stmt, query: newDriverQuery(query)}
+	if !ret.query.deferred() {
+		if _, err := ret.prepare(ctx, nil); err != nil {
+			stmt.Close()
+			return nil, err
+		}
+	}
+	return ret, nil
+}
+
+func (c *embedConn) Close() error {
+	return c.conn.Close()
+}
+
+// Begin returns error, the engine does not support transactions.
+func (c *embedConn) Begin() (driver.Tx, error) {
+	return nil, ErrDriverTxNotSupported()
+}
+
+func (c *embedConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	s, err := c.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer s.Close()
+	return s.(*embedStmt).ExecContext(ctx, args)
+}
+
+func (c *embedConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	s, err := c.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.(*embedStmt).QueryContext(ctx, args)
+	if err != nil {
+		s.Close()
+		return nil, err
+	}
+	// the statement is freed with the rows
+	rows.(*embedRows).closeStmt = true
+	return rows, nil
+}
+
+func (c *embedConn) Ping(ctx context.Context) error {
+	if !c.IsValid() {
+		return driver.ErrBadConn
+	}
+	return runContext(ctx, c.conn.Cancel, func() error { return pingConn(c.conn) })
+}
+
+func (c *embedConn) IsValid() bool {
+	return !c.conn.isClosed()
+}
+
+// CheckNamedValue passes the values that Bind() accepts as they are,
+// the others are converted by database/sql.
+func (c *embedConn) CheckNamedValue(nv *driver.NamedValue) error {
+	if _, ok := nv.Value.(driver.Valuer); ok {
+		return driver.ErrSkip
+	}
+	if _, err := bindNormalize(nv.Ordinal-1, nv.Value); err != nil {
+		return driver.ErrSkip
+	}
+	return nil
+}
+
+type embedStmt struct {
+	stmt  *Stmt
+	query *driverQuery
+}
+
+var _ driver.Stmt = (*embedStmt)(nil)
+var _ driver.StmtExecContext = (*embedStmt)(nil)
+var _ driver.StmtQueryContext = (*embedStmt)(nil)
+
+func (s *embedStmt) Close() error {
+	return s.stmt.Close()
+}
+
+// NumInput returns -1, the engine does not report the number of parameters.
+func (s *embedStmt) NumInput() int {
+	return -1
+}
+
+func (s *embedStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.ExecContext(context.Background(), namedValues(args))
+}
+
+func (s *embedStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.QueryContext(context.Background(), namedValues(args))
+}
+
+func (s *embedStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	if err := s.bind(ctx, args); err != nil {
+		return nil, err
+	}
+	defer s.stmt.ExecuteClean()
+	if err := runContext(ctx, s.stmt.conn.Cancel, s.stmt.Execute); err != nil {
+		return nil, err
+	}
+	n, err := EngEffectRows(s.stmt.handle)
+	if err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(n), nil
+}
+
+func (s *embedStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	if err := s.bind(ctx, args); err != nil {
+		return nil, err
+	}
+	if err := runContext(ctx, s.stmt.conn.Cancel, s.stmt.Execute); err != nil {
+		s.stmt.ExecuteClean()
+		return nil, err
+	}
+	columns, err := EngColumns(s.stmt.handle)
+	if err != nil {
+		s.stmt.ExecuteClean()
+		return nil, err
+	}
+	rows := &embedRows{stmt: s.stmt, ctx: ctx, resultColumns: resultColumns{columns: columns}}
+	return rows, nil
+}
+
+// prepare prepares the query for args if the statement is not prepared with it yet,
+// it returns args in the order of the placeholders.
+func (s *embedStmt) prepare(ctx context.Context, args []driver.NamedValue) ([]driver.NamedValue, error) {
+	sqlText, args, err := s.query.resolve(args)
+	if err != nil {
+		return nil, err
+	}
+	if sqlText != s.query.prepared {
+		s.query.prepared = ""
+		if err := runContext(ctx, s.stmt.conn.Cancel, func() error { return s.stmt.Prepare(sqlText) }); err != nil {
+			return nil, err
+		}
+		s.query.prepared = sqlText
+	}
+	return args, nil
+}
+
+func (s *embedStmt) bind(ctx context.Context, args []driver.NamedValue) error {
+	args, err := s.prepare(ctx, args)
+	if err != nil {
+		return err
+	}
+	for _, arg := range args {
+		if err := Bind(s.stmt.handle, arg.Ordinal-1, arg.Value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func namedValues(args []driver.Value) []driver.NamedValue {
+	ret := make([]driver.NamedValue, len(args))
+	for i, v := range args {
+		ret[i] = driver.NamedValue{Ordinal: i + 1, Value: v}
+	}
+	return ret
+}
+
+type embedRows struct {
+	resultColumns
+	stmt      *Stmt
+	ctx       context.Context
+	closeStmt bool
+}
+
+var _ driver.Rows = (*embedRows)(nil)
+var _ driver.RowsColumnTypeScanType = (*embedRows)(nil)
+var _ driver.RowsColumnTypeDatabaseTypeName = (*embedRows)(nil)
+var _ driver.RowsColumnTypeLength = (*embedRows)(nil)
+var _ driver.RowsColumnTypeNullable = (*embedRows)(nil)
+
+func (r *embedRows) Close() error {
+	err := r.stmt.ExecuteClean()
+	if r.closeStmt {
+		if e := r.stmt.Close(); e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+func (r *embedRows) Next(dest []driver.Value) error {
+	next, err := r.stmt.FetchContext(r.ctx)
+	if err != nil {
+		return err
+	}
+	if !next {
+		return io.EOF
+	}
+	for i := range dest {
+		value, valid, err := engColumnValue(r.stmt.handle, i)
+		if err != nil {
+			return err
+		}
+		if !valid {
+			dest[i] = nil
+			continue
+		}
+		dest[i] = driverValue(value)
+	}
+	return nil
+}
+
+// driverValue converts the column value into driver.Value.
+// uint64 that overflows int64 is converted into the decimal string, since driver.Value does not
+// have uint64. database/sql can scan the string into uint64, string and []byte.
+func driverValue(value any) driver.Value {
+	switch v := value.(type) {
+	case int16:
+		return int64(v)
+	case int32:
+		return int64(v)
+	case uint16:
+		return int64(v)
+	case uint32:
+		return int64(v)
+	case uint64:
+		if v > math.MaxInt64 {
+			return strconv.FormatUint(v, 10)
+		}
+		return int64(v)
+	case float32:
+		return float64(v)
+	case net.IP:
+		return v.String()
+	default:
+		return value
+	}
+}
+
+// resultColumns implements the column metadata of driver.Rows.
+type resultColumns struct {
+	columns []Column
+}
+
+func (r *resultColumns) Columns() []string {
+	ret := make([]string, len(r.columns))
+	for i, c := range r.columns {
+		ret[i] = c.Name
+	}
+	return ret
+}
+
+func (r *resultColumns) ColumnTypeScanType(index int) reflect.Type {
+	if t := r.columns[index].Type.ScanType(); t != nil {
+		return t
+	}
+	return reflect.TypeOf(new(any)).Elem()
+}
+
+func (r *resultColumns) ColumnTypeDatabaseTypeName(index int) string {
+	return r.columns[index].Type.DatabaseTypeName()
+}
+
+func (r *resultColumns) ColumnTypeLength(index int) (int64, bool) {
+	col := r.columns[index]
+	if !col.Type.Variable() {
+		return 0, false
+	}
+	return int64(col.Size), true
+}
+
+func (r *resultColumns) ColumnTypeNullable(index int) (bool, bool) {
+	col := r.columns[index]
+	if !col.NullableKnown {
+		return false, false
+	}
+	return col.Nullable, true
+}
@@ -0,0 +1,111 @@
+package mach_test
+
+import (
+	"context"
+	"database/sql"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	mach "github.com/machbase/neo-engine/v8"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEmbedDriver(t *testing.T) {
+	db, err := sql.Open(mach.EmbedDriverName, "HOME="+global.Env.HomeDir()+";UID=sys")
+	require.NoError(t, err)
+	defer db.Close()
+	require.NoError(t, db.Ping())
+
+	_, err = db.Exec(`create table driver_embed (name varchar(40), time datetime, value double)`)
+	require.NoError(t, err)
+	defer db.Exec(`drop table driver_embed`)
+
+	now := time.Now().Truncate(time.Millisecond)
+	for i := 0; i < 3; i++ {
+		_, err := db.Exec(`insert into driver_embed values(?, ?, ?)`, "driver-embed", now.Add(time.Duration(i)*time.Second), float64(i)+0.5)
+		require.NoError(t, err)
+	}
+	_, err = db.Exec(`EXEC table_flush(driver_embed)`)
+	require.NoError(t, err)
+
+	rows, err := db.Query(`select name, time, value from driver_embed where name = ? order by time`, "driver-embed")
+	require.NoError(t, err)
+	cols, err := rows.Columns()
+	require.NoError(t, err)
+	require.Equal(t, []string{"NAME", "TIME", "VALUE"}, cols)
+	colTypes, err := rows.ColumnTypes()
+	require.NoError(t, err)
+	require.Equal(t, "VARCHAR", colTypes[0].DatabaseTypeName())
+	length, ok := colTypes[0].Length()
+	require.True(t, ok)
+	require.Greater(t, length, int64(0))
+	_, ok = colTypes[0].Nullable()
+	require.False(t, ok, "the engine does not report nullability")
+	require.Equal(t, "DATETIME", colTypes[1].DatabaseTypeName())
+	require.Equal(t, reflect.TypeOf(float64(0)), colTypes[2].ScanType())
+	count := 0
+	for rows.Next() {
+		var name string
+		var ts time.Time
+		var value float64
+		require.NoError(t, rows.Scan(&name, &ts, &value))
+		require.Equal(t, "driver-embed", name)
+		require.Equal(t, now.Add(time.Duration(count)*time.Second).UnixNano(), ts.UnixNano())
+		require.Equal(t, float64(count)+0.5, value)
+		count++
+	}
+	require.NoError(t, rows.Err())
+	require.NoError(t, rows.Close())
+	require.Equal(t, 3, count)
+
+	// prepared statement is reused
+	stmt, err := db.Prepare(`select count(*) from driver_embed where name = ?`)
+	require.NoError(t, err)
+	for i := 0; i < 2; i++ {
+		var n int
+		require.NoError(t, stmt.QueryRow("driver-embed").Scan(&n))
+		require.Equal(t, 3, n)
+	}
+	require.NoError(t, stmt.Close())
+
+	// named parameters
+	var n int
+	err = db.QueryRow(`select count(*) from driver_embed where name = :name and value > @min`,
+		sql.Named("min", 1.0), sql.Named("name", "driver-embed")).Scan(&n)
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+	// the named placeholders of the prepared statement are rewritten at the execution
+	stmt, err = db.Prepare(`select count(*) from driver_embed where name = :name and value > @min`)
+	require.NoError(t, err)
+	for _, min := range []float64{0.0, 1.0} {
+		require.NoError(t, stmt.QueryRow(sql.Named("name", "driver-embed"), sql.Named("min", min)).Scan(&n))
+		require.Equal(t, 3-int(min), n)
+	}
+	require.NoError(t, stmt.Close())
+
+	// uint64 over math.MaxInt64 is scanned from the decimal string
+	_, err = db.Exec(`create table driver_embed_ulong (value ulong)`)
+	require.NoError(t, err)
+	defer db.Exec(`drop table driver_embed_ulong`)
+	_, err = db.Exec(`insert into driver_embed_ulong values(?)`, uint64(math.MaxUint64)-1)
+	require.NoError(t, err)
+	var ulong uint64
+	require.NoError(t, db.QueryRow(`select value from driver_embed_ulong`).Scan(&ulong))
+	require.Equal(t, uint64(math.MaxUint64)-1, ulong)
+
+	_, err = db.Begin()
+	require.Error(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = db.QueryContext(ctx, `select count(*) from driver_embed`)
+	require.ErrorIs(t, err, context.Canceled)
+
+	// the connector of the env
+	db2 := sql.OpenDB(global.Env.Connector("sys", ""))
+	defer db2.Close()
+	require.NoError(t, db2.QueryRow(`select count(*) from driver_embed where name = 'driver-embed'`).Scan(&n))
+	require.Equal(t, 3, n)
+}
@@ -0,0 +1,312 @@
+package mach
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+	"unsafe"
+)
+
+type EnvState int
+
+const (
+	EnvStateNone EnvState = iota
+	EnvStateInitialized
+	EnvStateCreated
+	EnvStateStarted
+	EnvStateStopped
+	EnvStateFinalized
+)
+
+func (st EnvState) String() string {
+	switch st {
+	case EnvStateNone:
+		return "none"
+	case EnvStateInitialized:
+		return "initialized"
+	case EnvStateCreated:
+		return "created"
+	case EnvStateStarted:
+		return "started"
+	case EnvStateStopped:
+		return "stopped"
+	case EnvStateFinalized:
+		return "finalized"
+	default:
+		return "unknown"
+	}
+}
+
+// Env owns an engine env handle and guards the order of
+// initialize, create, startup, shutdown and finalize.
+type Env struct {
+	mu      sync.Mutex
+	handle  unsafe.Pointer
+	homeDir string
+	port    int
+	flag    InitFlag
+	state   EnvState
+	config  *Config
+	refs    int
+
+	startedAt time.Time
+
+	conns    map[*Conn]struct{}
+	pools    map[*Pool]struct{}
+	draining bool
+}
+
+// NewEnv initializes the engine on homeDir.
+// machPort takes effect only when it is greater than 0.
+// If homeDir has conf/machbase.conf, it is validated before the initialization.
+// It returns error if another Env of the process is alive on the same homeDir or machPort.
+func NewEnv(homeDir string, machPort int, opts ...InitOption) (*Env, error) {
+	absHome, err := filepath.Abs(homeDir)
+	if err != nil {
+		return nil, err
+	}
+	registry.Lock()
+	defer registry.Unlock()
+	if other := registry.lookup(absHome, machPort); other != nil {
+		return nil, ErrEnvAlreadyInitialized(other.homeDir, other.port)
+	}
+	env, err := newEnv(absHome, machPort, opts...)
+	if err != nil {
+		return nil, err
+	}
+	registry.envs[absHome] = env
+	return env, nil
+}
+
+// SharedEnv returns the alive Env on homeDir if there is, otherwise initializes a new one.
+// Every SharedEnv() should be paired with a Close().
+// It returns error if the alive Env has a different port.
+func SharedEnv(homeDir string, machPort int, opts ...InitOption) (*Env, error) {
+	absHome, err := filepath.Abs(homeDir)
+	if err != nil {
+		return nil, err
+	}
+	registry.Lock()
+	defer registry.Unlock()
+	if other := registry.lookup(absHome, machPort); other != nil {
+		if other.homeDir != absHome || other.port != machPort {
+			return nil, ErrEnvAlreadyInitialized(other.homeDir, other.port)
+		}
+		other.mu.Lock()
+		other.refs++
+		other.mu.Unlock()
+		return other, nil
+	}
+	env, err := newEnv(absHome, machPort, opts...)
+	if err != nil {
+		return nil, err
+	}
+	registry.envs[absHome] = env
+	return env, nil
+}
+
+func newEnv(homeDir string, machPort int, opts ...InitOption) (*Env, error) {
+	env := &Env{
+		homeDir: homeDir,
+		port:    machPort,
+		flag:    InitFlags(opts...),
+		refs:    1,
+	}
+	confPath := filepath.Join(homeDir, "conf", ConfigFileName)
+	if _, err := os.Stat(confPath); err == nil {
+		conf, err := LoadConfig(confPath)
+		if err != nil {
+			return nil, err
+		}
+		if err := conf.Validate(); err != nil {
+			return nil, err
+		}
+		env.config = conf
+	}
+	if err := EngInitialize(homeDir, machPort, int(env.flag), &env.handle); err != nil {
+		return nil, err
+	}
+	env.state = EnvStateInitialized
+	return env, nil
+}
+
+// Handle returns the native env handle, it is nil after Close().
+func (env *Env) Handle() unsafe.Pointer {
+	env.mu.Lock()
+	defer env.mu.Unlock()
+	return env.handle
+}
+
+func (env *Env) HomeDir() string {
+	return env.homeDir
+}
+
+func (env *Env) Port() int {
+	return env.port
+}
+
+func (env *Env) Flag() InitFlag {
+	return env.flag
+}
+
+// Config returns the config that was loaded from the home directory,
+// it returns nil if there was no config file.
+func (env *Env) Config() *Config {
+	return env.config
+}
+
+func (env *Env) State() EnvState {
+	env.mu.Lock()
+	defer env.mu.Unlock()
+	return env.state
+}
+
+// check returns error if the current state is not one of the allowed.
+// the caller should hold the lock.
+func (env *Env) check(op string, allowed ...EnvState) error {
+	for _, st := range allowed {
+		if env.state == st {
+			return nil
+		}
+	}
+	return ErrEnvInvalidState(op, env.state)
+}
+
+func (env *Env) ExistsDatabase() bool {
+	env.mu.Lock()
+	defer env.mu.Unlock()
+	if env.handle == nil {
+		return false
+	}
+	return EngExistsDatabase(env.handle)
+}
+
+func (env *Env) CreateDatabase() error {
+	env.mu.Lock()
+	defer env.mu.Unlock()
+	if err := env.check("CreateDatabase", EnvStateInitialized, EnvStateStopped); err != nil {
+		return err
+	}
+	if err := EngCreateDatabase(env.handle); err != nil {
+		return err
+	}
+	env.state = EnvStateCreated
+	return nil
+}
+
+func (env *Env) DestroyDatabase() error {
+	env.mu.Lock()
+	defer env.mu.Unlock()
+	if err := env.check("DestroyDatabase", EnvStateInitialized, EnvStateCreated, EnvStateStopped); err != nil {
+		return err
+	}
+	if err := EngDestroyDatabase(env.handle); err != nil {
+		return err
+	}
+	env.state = EnvStateInitialized
+	return nil
+}
+
+func (env *Env) Startup() error {
+	env.mu.Lock()
+	defer env.mu.Unlock()
+	if err := env.check("Startup", EnvStateInitialized, EnvStateCreated); err != nil {
+		return err
+	}
+	if !EngExistsDatabase(env.handle) {
+		return ErrEnvDatabaseNotExists(env.homeDir)
+	}
+	if err := EngStartup(env.handle); err != nil {
+		return err
+	}
+	env.state = EnvStateStarted
+	env.startedAt = time.Now()
+	return nil
+}
+
+func (env *Env) Shutdown() error {
+	env.mu.Lock()
+	defer env.mu.Unlock()
+	if err := env.check("Shutdown", EnvStateStarted); err != nil {
+		return err
+	}
+	if err := EngShutdown(env.handle); err != nil {
+		return err
+	}
+	env.state = EnvStateStopped
+	return nil
+}
+
+// Close shuts down the database if it is running, then finalizes the env.
+// It is safe to call Close() more than once.
+// If the env is shared by SharedEnv(), only the last Close() finalizes it.
+// If the shutdown fails, the env is left started and not finalized.
+func (env *Env) Close() error {
+	return env.close(false)
+}
+
+func (env *Env) close(force bool) error {
+	env.mu.Lock()
+	if env.state == EnvStateFinalized || env.state == EnvStateNone {
+		env.mu.Unlock()
+		return nil
+	}
+	if !force && env.refs > 1 {
+		env.refs--
+		env.mu.Unlock()
+		return nil
+	}
+	// the connections are closed before the engine is finalized,
+	// Close() and Release() of them after this do not touch the engine.
+	// They are closed without the lock since it may wait for the running statements,
+	// and the new connections are refused meanwhile.
+	draining := env.draining
+	env.draining = true
+	pools := make([]*Pool, 0, len(env.pools))
+	for pool := range env.pools {
+		pools = append(pools, pool)
+	}
+	env.pools = nil
+	conns := make([]*Conn, 0, len(env.conns))
+	for conn := range env.conns {
+		conns = append(conns, conn)
+	}
+	env.mu.Unlock()
+
+	for _, pool := range pools {
+		pool.shutdown(true)
+	}
+	for _, conn := range conns {
+		if err := conn.close(); err != nil && !conn.isClosed() {
+			env.mu.Lock()
+			env.draining = draining
+			env.mu.Unlock()
+			return err
+		}
+	}
+
+	env.mu.Lock()
+	if env.state == EnvStateFinalized {
+		// closed by another Close() meanwhile
+		env.mu.Unlock()
+		return nil
+	}
+	env.conns = nil
+	if env.state == EnvStateStarted {
+		if err := EngShutdown(env.handle); err != nil {
+			env.draining = draining
+			env.mu.Unlock()
+			return err
+		}
+		env.state = EnvStateStopped
+	}
+	EngFinalize(env.handle)
+	env.handle = nil
+	env.state = EnvStateFinalized
+	env.refs = 0
+	env.mu.Unlock()
+
+	unregisterEnv(env)
+	return nil
+}
@@ -0,0 +1,69 @@
+package mach_test
+
+import (
+	"testing"
+	"unsafe"
+
+	mach "github.com/machbase/neo-engine/v8"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnvState(t *testing.T) {
+	env := global.Env
+	require.Equal(t, mach.EnvStateStarted, env.State())
+	require.Equal(t, "started", env.State().String())
+	require.True(t, env.ExistsDatabase())
+
+	// invalid transitions while the database is running
+	require.Error(t, env.Startup())
+	require.Error(t, env.CreateDatabase())
+	require.Error(t, env.DestroyDatabase())
+	require.Equal(t, mach.EnvStateStarted, env.State())
+}
+
+func TestEnvRegistry(t *testing.T) {
+	env := global.Env
+
+	// duplicated initialization is rejected
+	_, err := mach.NewEnv(env.HomeDir(), env.Port())
+	require.Error(t, err)
+	_, err = mach.NewEnv(t.TempDir(), env.Port())
+	require.Error(t, err)
+	_, err = mach.SharedEnv(env.HomeDir(), env.Port()+1)
+	require.Error(t, err)
+
+	// the other tests may have their envs and references,
+	// so the env is looked up by its home and the references are counted from here
+	info := envInfo(t, env.HomeDir())
+	require.Equal(t, "started", info.State)
+	refs := info.Refs
+
+	// shared
+	shared, err := mach.SharedEnv(env.HomeDir(), env.Port())
+	require.NoError(t, err)
+	require.Same(t, env, shared)
+	require.Equal(t, refs+1, envInfo(t, env.HomeDir()).Refs)
+
+	// Close() of the shared one only releases the reference
+	require.NoError(t, shared.Close())
+	require.Equal(t, mach.EnvStateStarted, env.State())
+	require.Equal(t, refs, envInfo(t, env.HomeDir()).Refs)
+
+	// the CLI env of TestMain, and the one of the CLI driver if it has been used
+	cliEnvs := mach.CliEnvCount()
+	require.GreaterOrEqual(t, cliEnvs, 1)
+	var unknown int
+	require.Error(t, mach.CliFinalize(unsafe.Pointer(&unknown)))
+	require.Equal(t, cliEnvs, mach.CliEnvCount())
+}
+
+func envInfo(t *testing.T, homeDir string) mach.EnvInfo {
+	t.Helper()
+	for _, info := range mach.Envs() {
+		if info.HomeDir == homeDir {
+			return info
+		}
+	}
+	t.Fatalf("env of %s is not found", homeDir)
+	return mach.EnvInfo{}
+}
@@ -1,16 +1,30 @@
 package mach
 
-import "fmt"
+import (
+	"fmt"
+	"time"
+)
 
 var ErrDatabaseMach = func(code int, msg string) error {
 	return fmt.Errorf("MACH-ERR %d %s", code, msg)
 }
 
 var ErrDatabaseCli = func(fn string, code int, msg string) error {
-	if code == 0 {
-		return fmt.Errorf("MACHCLI-ERR %s, %s", msg, fn)
+	return &CliErr{Fn: fn, Code: code, Msg: msg}
+}
+
+// CliErr is the error that MachCLIError() reports, use errors.As() to get the code.
+type CliErr struct {
+	Fn   string
+	Code int
+	Msg  string
+}
+
+func (e *CliErr) Error() string {
+	if e.Code == 0 {
+		return fmt.Sprintf("MACHCLI-ERR %s, %s", e.Msg, e.Fn)
 	} else {
-		return fmt.Errorf("MACHCLI-ERR %d %s, %s", code, msg, fn)
+		return fmt.Sprintf("MACHCLI-ERR %d %s, %s", e.Code, e.Msg, e.Fn)
 	}
 }
 
@@ -35,6 +49,147 @@ var ErrDatabaseAppendWrongTimeValueType = func(actual string, column string, typ
 var ErrDatabaseAppendWrongTimeStringType = func(column string, typ string) error {
 	return fmt.Errorf("MachAppendData cannot apply string without format to %s (%s)", column, typ)
 }
+var ErrDatabaseUnsupportedType = func(fn string, typ int) error {
+	return fmt.Errorf("%s unsupported column type %d", fn, typ)
+}
 var ErrDatabaseAppendWrongValueCount = func(expect int, actual int) error {
 	return fmt.Errorf("MachAppendData required %d, but got %d", expect, actual)
 }
+var ErrEnvInvalidState = func(op string, state EnvState) error {
+	return fmt.Errorf("MachEnv %s is not allowed in %s state", op, state)
+}
+var ErrEnvDatabaseNotExists = func(homeDir string) error {
+	return fmt.Errorf("MachEnv database does not exist in %s", homeDir)
+}
+var ErrConfigSyntax = func(lineNo int, line string) error {
+	return fmt.Errorf("MachConfig invalid syntax at line %d: %q", lineNo, line)
+}
+var ErrConfigAtLine = func(lineNo int, cause error) error {
+	return fmt.Errorf("MachConfig line %d %s", lineNo, cause.Error())
+}
+var ErrConfigInvalidValue = func(name string, value string) error {
+	return fmt.Errorf("MachConfig invalid value of %s: %q", name, value)
+}
+var ErrConfigOutOfRange = func(name string, value int64, min string, max string) error {
+	if max == "" {
+		return fmt.Errorf("MachConfig %s %d is out of range, min %s", name, value, min)
+	}
+	return fmt.Errorf("MachConfig %s %d is out of range, min %s max %s", name, value, min, max)
+}
+var ErrHomeNotWritable = func(dir string, cause error) error {
+	return fmt.Errorf("MachHome %s is not writable, %s", dir, cause.Error())
+}
+var ErrHomeDiskSpace = func(dir string, free uint64, required uint64) error {
+	return fmt.Errorf("MachHome %s has %d bytes free, but %d bytes required", dir, free, required)
+}
+var ErrEnvNotReady = func(stage string, cause error, last error) error {
+	return fmt.Errorf("MachEnv not ready at %s, %w: %w", stage, cause, last)
+}
+var ErrDatabaseNoRows = func(sqlText string) error {
+	return fmt.Errorf("no rows from %q", sqlText)
+}
+var ErrEnvDraining = func(homeDir string) error {
+	return fmt.Errorf("MachEnv %s is shutting down", homeDir)
+}
+var ErrEnvShutdownBusy = func(canceled int) error {
+	return fmt.Errorf("MachEnv shutdown canceled %d sessions, but statements are still running", canceled)
+}
+var ErrConnClosed = func(sessionID uint64) error {
+	return fmt.Errorf("MachConn session %d is closed", sessionID)
+}
+var ErrCliDataTruncated = func(column string, length int, read int) error {
+	return fmt.Errorf("MachCLIGetData column %s has %d bytes, but read %d bytes", column, length, read)
+}
+var ErrConnBusy = func(sessionID uint64) error {
+	return fmt.Errorf("MachConn session %d is canceled, but statements are still running", sessionID)
+}
+var ErrPoolClosed = func() error {
+	return fmt.Errorf("MachPool is closed")
+}
+var ErrCliDriverBusy = func(conns int) error {
+	return fmt.Errorf("MachCliDriver %d connections are open", conns)
+}
+var ErrCliPoolClosed = func() error {
+	return fmt.Errorf("MachCliPool is closed")
+}
+var ErrStmtClosed = func(sessionID uint64) error {
+	return fmt.Errorf("MachStmt of session %d is closed", sessionID)
+}
+var ErrDatabaseCanceled = func(cause error, dbErr error) error {
+	if dbErr == nil {
+		return fmt.Errorf("canceled, %w", cause)
+	}
+	return fmt.Errorf("canceled, %w: %w", cause, dbErr)
+}
+var ErrBackupInvalidTable = func(table string) error {
+	return fmt.Errorf("MachBackup invalid table name %q", table)
+}
+var ErrBackupInvalidRange = func(from time.Time, to time.Time) error {
+	return fmt.Errorf("MachBackup invalid time range from %s to %s", from, to)
+}
+var ErrBackupExists = func(path string) error {
+	return fmt.Errorf("MachBackup %s already exists", path)
+}
+var ErrBackupInvalidDir = func(path string) error {
+	return fmt.Errorf("MachBackup %s is not a backup directory", path)
+}
+var ErrBackupDatabaseExists = func(homeDir string) error {
+	return fmt.Errorf("MachRestore database already exists in %s", homeDir)
+}
+var ErrEnvSignalHandlerOn = func(flag InitFlag) error {
+	return fmt.Errorf("MachEnv native signal handler takes SIGINT (flag %d)", flag)
+}
+var ErrEnvAlreadyInitialized = func(homeDir string, port int) error {
+	return fmt.Errorf("MachEnv already initialized on %s port %d", homeDir, port)
+}
+var ErrCliEnvNotInitialized = func() error {
+	return fmt.Errorf("MachCLIFinalize env is not initialized or already finalized")
+}
+var ErrDriverInvalidDSN = func(part string) error {
+	return fmt.Errorf("MachDriver invalid DSN part %q", part)
+}
+var ErrDriverEnvNotFound = func(homeDir string) error {
+	if homeDir == "" {
+		return fmt.Errorf("MachDriver HOME is required unless only one env is alive")
+	}
+	return fmt.Errorf("MachDriver env of %s is not found", homeDir)
+}
+var ErrDriverTxNotSupported = func() error {
+	return fmt.Errorf("MachDriver transaction is not supported")
+}
+var ErrNamedParamMixed = func() error {
+	return fmt.Errorf("named and positional parameters are mixed")
+}
+var ErrNamedParamMissing = func(name string) error {
+	return fmt.Errorf("named parameter %q is missing", name)
+}
+var ErrNamedParamArg = func(arg any) error {
+	return fmt.Errorf("named parameters require map[string]any or struct, but got %T", arg)
+}
+var ErrBindOverflow = func(idx int, value any, typ ColumnType) error {
+	return &BindOverflowErr{Idx: idx, Value: value, Type: typ}
+}
+var ErrBindUnsupportedType = func(idx int, value any, typ ColumnType) error {
+	if typ < 0 {
+		return fmt.Errorf("MachBind unsupported value %T at %d", value, idx)
+	}
+	return fmt.Errorf("MachBind cannot bind %T to %s at %d", value, typ, idx)
+}
+var ErrBindNotNullable = func(idx int, typ ColumnType) error {
+	return fmt.Errorf("MachBind NULL to not nullable %s at %d", typ, idx)
+}
+var ErrScanColumnCount = func(columns int, dest int) error {
+	return fmt.Errorf("MachScan expected %d destinations, but got %d", columns, dest)
+}
+var ErrScanInvalidDest = func(idx int, dest any) error {
+	return fmt.Errorf("MachScan destination %T is not a non-nil pointer at %d", dest, idx)
+}
+var ErrScanNull = func(idx int, dest any) error {
+	return fmt.Errorf("MachScan NULL into %T at %d", dest, idx)
+}
+var ErrScanConvert = func(idx int, value any, dest any) error {
+	return fmt.Errorf("MachScan cannot convert %v (%T) into %T at %d", value, value, dest, idx)
+}
+var ErrScanNoRow = func() error {
+	return fmt.Errorf("MachScan no row is fetched")
+}
@@ -0,0 +1,153 @@
+package mach
+
+import (
+	"strings"
+)
+
+// PlanNode is an operator of the plan that EngExplain() and CliExplain() return.
+//
+//	PROJECT
+//	 TAG READ (RAW)
+//	  KEYVALUE INDEX SCAN (_TAG_DATA_0_META)
+//	   [KEY RANGE]
+//	    * IN ()
+//
+// "TAG READ (RAW)" is the node of Name "TAG READ" and Attrs ["RAW"].
+// "[KEY RANGE]" and "* IN ()" are the Details of "KEYVALUE INDEX SCAN".
+type PlanNode struct {
+	Name     string      // the operator without the attributes
+	Attrs    []string    // the texts in the parentheses after the operator
+	Details  []string    // the lines under the operator that are not operators, trimmed
+	Children []*PlanNode // the operators under the operator
+}
+
+// ParsePlan parses the indented plan text into the trees of the operators.
+// A line is an operator under the closest less indented operator,
+// except a section line in brackets (e.g. "[KEY RANGE]") that begins the details:
+// it and the lines indented more than it are the details of the operator above them.
+func ParsePlan(plan string) []*PlanNode {
+	type level struct {
+		indent int
+		node   *PlanNode
+	}
+	var roots []*PlanNode
+	var stack []level
+	sectionIndent := -1
+	for _, line := range strings.Split(plan, "\n") {
+		text := strings.TrimSpace(line)
+		if text == "" {
+			continue
+		}
+		indent := len(line) - len(strings.TrimLeft(line, " \t"))
+		if sectionIndent >= 0 && indent > sectionIndent {
+			parent := stack[len(stack)-1].node
+			parent.Details = append(parent.Details, text)
+			continue
+		}
+		sectionIndent = -1
+		for len(stack) > 0 && stack[len(stack)-1].indent >= indent {
+			stack = stack[:len(stack)-1]
+		}
+		if len(stack) > 0 && planIsSection(text) {
+			parent := stack[len(stack)-1].node
+			parent.Details = append(parent.Details, text)
+			sectionIndent = indent
+			continue
+		}
+		node := planParseNode(text)
+		if len(stack) == 0 {
+			roots = append(roots, node)
+		} else {
+			parent := stack[len(stack)-1].node
+			parent.Children = append(parent.Children, node)
+		}
+		stack = append(stack, level{indent: indent, node: node})
+	}
+	return roots
+}
+
+func planIsSection(text string) bool {
+	return strings.HasPrefix(text, "[") && strings.HasSuffix(text, "]")
+}
+
+// planParseNode splits the trailing "(...)" attributes from the operator.
+func planParseNode(text string) *PlanNode {
+	ret := &PlanNode{}
+	for strings.HasSuffix(text, ")") {
+		depth, open := 0, -1
+		for i := len(text) - 1; i >= 0; i-- {
+			if text[i] == ')' {
+				depth++
+			} else if text[i] == '(' {
+				if depth--; depth == 0 {
+					open = i
+					break
+				}
+			}
+		}
+		if open <= 0 {
+			break
+		}
+		ret.Attrs = append([]string{strings.TrimSpace(text[open+1 : len(text)-1])}, ret.Attrs...)
+		text = strings.TrimSpace(text[:open])
+	}
+	ret.Name = text
+	return ret
+}
+
+// Walk calls fn for the node and its descendants in depth-first order with the depth from the node.
+func (n *PlanNode) Walk(fn func(node *PlanNode, depth int)) {
+	n.walk(fn, 0)
+}
+
+func (n *PlanNode) walk(fn func(*PlanNode, int), depth int) {
+	fn(n, depth)
+	for _, c := range n.Children {
+		c.walk(fn, depth+1)
+	}
+}
+
+// Find returns the first node of the name in depth-first order, the name is case-insensitive.
+func (n *PlanNode) Find(name string) *PlanNode {
+	if strings.EqualFold(n.Name, name) {
+		return n
+	}
+	for _, c := range n.Children {
+		if ret := c.Find(name); ret != nil {
+			return ret
+		}
+	}
+	return nil
+}
+
+// String renders the node and its descendants in the indented plan text.
+func (n *PlanNode) String() string {
+	sb := &strings.Builder{}
+	n.Walk(func(node *PlanNode, depth int) {
+		indent := strings.Repeat(" ", depth)
+		sb.WriteString(indent)
+		sb.WriteString(node.Name)
+		for _, attr := range node.Attrs {
+			sb.WriteString(" (")
+			sb.WriteString(attr)
+			sb.WriteString(")")
+		}
+		sb.WriteString("\n")
+		// the lines under "[...]" are indented more
+		section := ""
+		for _, d := range node.Details {
+			if strings.HasPrefix(d, "[") {
+				section = ""
+			}
+			sb.WriteString(indent)
+			sb.WriteString(" ")
+			sb.WriteString(section)
+			sb.WriteString(d)
+			sb.WriteString("\n")
+			if strings.HasPrefix(d, "[") {
+				section = " "
+			}
+		}
+	})
+	return sb.String()
+}
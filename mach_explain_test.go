@@ -0,0 +1,93 @@
+package mach_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"unsafe"
+
+	mach "github.com/machbase/neo-engine/v8"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePlan(t *testing.T) {
+	plan := ` PROJECT
+  TAG READ (RAW)
+   KEYVALUE INDEX SCAN (_TAG_DATA_0_META)
+    [KEY RANGE]
+     * IN ()
+      NAME = 'a'
+   VOLATILE INDEX SCAN (_TAG_DATA_0_META) (SKIP(1))
+ GROUP BY (KEY 1, HASH)
+`
+	roots := mach.ParsePlan(plan)
+	require.Len(t, roots, 2)
+	require.Equal(t, "PROJECT", roots[0].Name)
+	require.Equal(t, "GROUP BY", roots[1].Name)
+	require.Equal(t, []string{"KEY 1, HASH"}, roots[1].Attrs)
+
+	read := roots[0].Find("tag read")
+	require.NotNil(t, read)
+	require.Equal(t, []string{"RAW"}, read.Attrs)
+	require.Len(t, read.Children, 2)
+	require.Equal(t, []string{"[KEY RANGE]", "* IN ()", "NAME = 'a'"}, read.Children[0].Details)
+	require.Equal(t, "VOLATILE INDEX SCAN", read.Children[1].Name)
+	require.Equal(t, []string{"_TAG_DATA_0_META", "SKIP(1)"}, read.Children[1].Attrs)
+	require.Nil(t, roots[0].Find("not exists"))
+
+	var names []string
+	roots[0].Walk(func(node *mach.PlanNode, depth int) {
+		names = append(names, fmt.Sprintf("%d:%s", depth, node.Name))
+	})
+	require.Equal(t, []string{"0:PROJECT", "1:TAG READ", "2:KEYVALUE INDEX SCAN", "2:VOLATILE INDEX SCAN"}, names)
+	require.Equal(t, roots[0].String(), mach.ParsePlan(roots[0].String())[0].String())
+
+	// the operators are found by the indentation, not by the letters
+	roots = mach.ParsePlan(" PROJECT\n  _rollup SCAN\n   [FILTER]\n    Value > 1\n  * MERGE\n")
+	require.Len(t, roots, 1)
+	require.Len(t, roots[0].Children, 2)
+	require.Equal(t, "_rollup SCAN", roots[0].Children[0].Name)
+	require.Equal(t, []string{"[FILTER]", "Value > 1"}, roots[0].Children[0].Details)
+	require.Equal(t, "* MERGE", roots[0].Children[1].Name)
+}
+
+func TestExplain(t *testing.T) {
+	conn, err := global.Env.ConnectTrust("sys")
+	require.NoError(t, err)
+	defer conn.Close()
+	stmt, err := conn.NewStmt()
+	require.NoError(t, err)
+	defer stmt.Close()
+
+	query := `select name from m$sys_users where name = 'SYS'`
+	require.NoError(t, stmt.Prepare(query))
+	plan, err := mach.EngExplain(stmt.Handle(), false)
+	require.NoError(t, err)
+	roots := mach.ParsePlan(plan)
+	require.NotEmpty(t, roots)
+	require.NoError(t, stmt.ExecuteClean())
+
+	// the plan larger than the initial buffer
+	selects := make([]string, 500)
+	for i := range selects {
+		selects[i] = fmt.Sprintf(`select name from m$sys_users where name = 'user-%d'`, i)
+	}
+	require.NoError(t, stmt.Prepare(strings.Join(selects, " union all ")))
+	plan, err = mach.EngExplain(stmt.Handle(), false)
+	require.NoError(t, err)
+	require.Greater(t, len(plan), 16*1024)
+	require.NoError(t, stmt.ExecuteClean())
+
+	var cliConn unsafe.Pointer
+	err = mach.CliConnect(global.CliEnv, fmt.Sprintf("SERVER=127.0.0.1;UID=SYS;PWD=MANAGER;CONNTYPE=1;PORT_NO=%d", machPort), &cliConn)
+	require.NoError(t, err)
+	defer mach.CliDisconnect(cliConn)
+	var cliStmt unsafe.Pointer
+	require.NoError(t, mach.CliAllocStmt(cliConn, &cliStmt))
+	defer mach.CliFreeStmt(cliStmt)
+	cliPlan, err := mach.CliExplain(cliStmt, query)
+	require.NoError(t, err)
+	require.Equal(t, len(roots), len(mach.ParsePlan(cliPlan)))
+	_, err = mach.CliExplain(cliStmt, `select * from not_exists_table`)
+	require.Error(t, err)
+}
@@ -0,0 +1,138 @@
+package mach
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// DefaultHomeMinFreeSpace is the free disk space that SetupHome() requires by default.
+const DefaultHomeMinFreeSpace = 256 * sizeMB
+
+// Home is the directory layout of machbase home.
+//
+//	<home>/conf/machbase.conf
+//	<home>/trc
+//	<home>/dbs
+type Home struct {
+	Dir    string
+	Config *Config
+
+	minFreeSpace uint64
+	temporary    bool
+}
+
+type HomeOption func(*Home)
+
+// HomeMinFreeSpace sets the free disk space that is required for the home directory,
+// 0 disables the check.
+func HomeMinFreeSpace(size uint64) HomeOption {
+	return func(h *Home) {
+		h.minFreeSpace = size
+	}
+}
+
+// SetupHome creates the directories of machbase home at dir and writes conf into it.
+// If conf is nil, the existing config file is kept or DefaultConfig() is written.
+func SetupHome(dir string, conf *Config, opts ...HomeOption) (*Home, error) {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	home := &Home{Dir: absDir, Config: conf, minFreeSpace: DefaultHomeMinFreeSpace}
+	for _, o := range opts {
+		o(home)
+	}
+	if err := home.setup(); err != nil {
+		return nil, err
+	}
+	return home, nil
+}
+
+// TempHome creates machbase home in a new temporary directory,
+// the directory is removed when Close() is called.
+func TempHome(conf *Config, opts ...HomeOption) (*Home, error) {
+	dir, err := os.MkdirTemp("", "machbase-home-")
+	if err != nil {
+		return nil, err
+	}
+	home := &Home{Dir: dir, Config: conf, minFreeSpace: DefaultHomeMinFreeSpace, temporary: true}
+	for _, o := range opts {
+		o(home)
+	}
+	if err := home.setup(); err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+	return home, nil
+}
+
+// setup validates the config before making any directory.
+func (home *Home) setup() error {
+	confDir := filepath.Join(home.Dir, "conf")
+	if home.Config == nil {
+		if conf, err := LoadConfig(home.ConfPath()); err == nil {
+			home.Config = conf
+		} else if os.IsNotExist(err) {
+			home.Config = DefaultConfig()
+		} else {
+			return err
+		}
+	}
+	if err := home.Config.Validate(); err != nil {
+		return err
+	}
+	dirs := []string{confDir, home.TraceLogDir(), home.DbsDir()}
+	for _, d := range dirs {
+		if err := os.MkdirAll(d, 0755); err != nil {
+			return err
+		}
+		if err := checkWritable(d); err != nil {
+			return err
+		}
+	}
+	if home.minFreeSpace > 0 {
+		free, err := diskFreeSpace(home.DbsDir())
+		if err != nil {
+			return err
+		}
+		if free < home.minFreeSpace {
+			return ErrHomeDiskSpace(home.DbsDir(), free, home.minFreeSpace)
+		}
+	}
+	return home.Config.WriteFile(home.ConfPath())
+}
+
+func checkWritable(dir string) error {
+	f, err := os.CreateTemp(dir, ".probe-")
+	if err != nil {
+		return ErrHomeNotWritable(dir, err)
+	}
+	f.Close()
+	return os.Remove(f.Name())
+}
+
+func (home *Home) ConfPath() string {
+	return filepath.Join(home.Dir, "conf", ConfigFileName)
+}
+
+func (home *Home) DbsDir() string {
+	return home.Config.DbsDir(home.Dir)
+}
+
+func (home *Home) TraceLogDir() string {
+	return home.Config.TraceLogDir(home.Dir)
+}
+
+// Temporary returns true if the home was made by TempHome().
+func (home *Home) Temporary() bool {
+	return home.temporary
+}
+
+// Close removes the home directory if it is temporary, otherwise does nothing.
+func (home *Home) Close() error {
+	if !home.temporary {
+		return nil
+	}
+	home.temporary = false
+	return os.RemoveAll(home.Dir)
+}
@@ -0,0 +1,44 @@
+package mach_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	mach "github.com/machbase/neo-engine/v8"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTempHome(t *testing.T) {
+	home, err := mach.TempHome(nil, mach.HomeMinFreeSpace(0))
+	require.NoError(t, err)
+	require.True(t, home.Temporary())
+
+	for _, dir := range []string{"conf", "trc", "dbs"} {
+		st, err := os.Stat(filepath.Join(home.Dir, dir))
+		require.NoError(t, err)
+		require.True(t, st.IsDir())
+	}
+	conf, err := mach.LoadConfig(home.ConfPath())
+	require.NoError(t, err)
+	require.Equal(t, mach.DefaultConfig(), conf)
+
+	require.NoError(t, home.Close())
+	_, err = os.Stat(home.Dir)
+	require.True(t, os.IsNotExist(err))
+	require.NoError(t, home.Close())
+
+	// invalid config is rejected before the directories are made
+	conf = mach.DefaultConfig()
+	conf.CPUParallel = 0
+	_, err = mach.TempHome(conf)
+	require.ErrorContains(t, err, "CPU_PARALLEL")
+	dir := filepath.Join(t.TempDir(), "home")
+	_, err = mach.SetupHome(dir, conf)
+	require.ErrorContains(t, err, "CPU_PARALLEL")
+	require.NoDirExists(t, dir)
+
+	// not enough disk space
+	_, err = mach.TempHome(nil, mach.HomeMinFreeSpace(1<<62))
+	require.Error(t, err)
+}
@@ -0,0 +1,301 @@
+package mach
+
+import (
+	"database/sql/driver"
+	"reflect"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+	"unsafe"
+)
+
+// NamedQuery is the query of which `:name` and `@name` placeholders are rewritten into `?`.
+type NamedQuery struct {
+	// SQL is the rewritten query with the positional markers.
+	SQL string
+	// Names are the names of the markers in the order of appearance,
+	// a name appears as many times as it is used.
+	Names []string
+}
+
+// ParseNamed rewrites the `:name` and `@name` placeholders of query into `?`.
+// The placeholders in string literals, quoted identifiers and comments are not touched.
+// It returns error if query has both of the named and positional markers.
+func ParseNamed(query string) (*NamedQuery, error) {
+	ret := &NamedQuery{}
+	sb := &strings.Builder{}
+	sb.Grow(len(query))
+	positional := false
+	for i := 0; i < len(query); {
+		c := query[i]
+		switch {
+		case c == '\'' || c == '"':
+			end := sqlQuotedEnd(query, i)
+			sb.WriteString(query[i:end])
+			i = end
+		case c == '-' && strings.HasPrefix(query[i:], "--"):
+			end := strings.IndexByte(query[i:], '\n')
+			if end < 0 {
+				end = len(query) - i
+			}
+			sb.WriteString(query[i : i+end])
+			i += end
+		case c == '/' && strings.HasPrefix(query[i:], "/*"):
+			end := strings.Index(query[i+2:], "*/")
+			if end < 0 {
+				end = len(query) - i
+			} else {
+				end += 4
+			}
+			sb.WriteString(query[i : i+end])
+			i += end
+		case c == ':' && strings.HasPrefix(query[i:], "::"):
+			// not a placeholder, e.g. a cast
+			sb.WriteString("::")
+			i += 2
+		case c == ':' || c == '@':
+			n := namedLen(query[i+1:])
+			if n == 0 {
+				sb.WriteByte(c)
+				i++
+				continue
+			}
+			ret.Names = append(ret.Names, query[i+1:i+1+n])
+			sb.WriteByte('?')
+			i += 1 + n
+		case c == '?':
+			positional = true
+			sb.WriteByte(c)
+			i++
+		default:
+			sb.WriteByte(c)
+			i++
+		}
+	}
+	if positional && len(ret.Names) > 0 {
+		return nil, ErrNamedParamMixed()
+	}
+	ret.SQL = sb.String()
+	return ret, nil
+}
+
+// sqlQuotedEnd returns the index next to the closing quote of the quoted text at query[i].
+// The quote in the text is escaped by doubling it.
+func sqlQuotedEnd(query string, i int) int {
+	c := query[i]
+	end := i + 1
+	for end < len(query) {
+		if query[end] == c {
+			if end+1 < len(query) && query[end+1] == c {
+				end += 2
+				continue
+			}
+			return end + 1
+		}
+		end++
+	}
+	return end
+}
+
+// namedLen returns the byte length of the name at the beginning of s, 0 if there is no name.
+func namedLen(s string) int {
+	n := 0
+	for n < len(s) {
+		r, size := utf8.DecodeRuneInString(s[n:])
+		if r == '_' || unicode.IsLetter(r) || (n > 0 && unicode.IsDigit(r)) {
+			n += size
+			continue
+		}
+		break
+	}
+	return n
+}
+
+// Args returns the positional values of the names from arg,
+// arg is map[string]any, or a struct or a pointer to a struct.
+// The fields of the struct are matched by the `db` tag or the field name in case-insensitive.
+func (nq *NamedQuery) Args(arg any) ([]any, error) {
+	lookup, err := namedLookup(arg)
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]any, len(nq.Names))
+	for i, name := range nq.Names {
+		v, ok := lookup(name)
+		if !ok {
+			return nil, ErrNamedParamMissing(name)
+		}
+		ret[i] = v
+	}
+	return ret, nil
+}
+
+func namedLookup(arg any) (func(string) (any, bool), error) {
+	switch m := arg.(type) {
+	case map[string]any:
+		return func(name string) (any, bool) {
+			v, ok := m[name]
+			return v, ok
+		}, nil
+	}
+	rv := reflect.ValueOf(arg)
+	for rv.Kind() == reflect.Pointer && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil, ErrNamedParamArg(arg)
+	}
+	fields := map[string]reflect.Value{}
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		f := rt.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name := f.Name
+		if tag, ok := f.Tag.Lookup("db"); ok {
+			if tag == "-" {
+				continue
+			}
+			if tag, _, _ = strings.Cut(tag, ","); tag != "" {
+				name = tag
+			}
+		}
+		fields[strings.ToLower(name)] = rv.Field(i)
+	}
+	return func(name string) (any, bool) {
+		if fv, ok := fields[strings.ToLower(name)]; ok {
+			return fv.Interface(), true
+		}
+		return nil, false
+	}, nil
+}
+
+// PrepareNamed prepares the query that has `:name` or `@name` placeholders,
+// the values are bound by BindNamed() with the returned NamedQuery.
+func (stmt *Stmt) PrepareNamed(query string) (*NamedQuery, error) {
+	nq, err := ParseNamed(query)
+	if err != nil {
+		return nil, err
+	}
+	if err := stmt.Prepare(nq.SQL); err != nil {
+		return nil, err
+	}
+	return nq, nil
+}
+
+// BindNamed binds the values of arg to the placeholders of nq, see NamedQuery.Args() for arg.
+func (stmt *Stmt) BindNamed(nq *NamedQuery, arg any) error {
+	args, err := nq.Args(arg)
+	if err != nil {
+		return err
+	}
+	for i, v := range args {
+		if err := Bind(stmt.handle, i, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CliPrepareNamed prepares the query that has `:name` or `@name` placeholders,
+// nq.Args() returns the values to bind in order.
+func CliPrepareNamed(stmt unsafe.Pointer, query string) (*NamedQuery, error) {
+	nq, err := ParseNamed(query)
+	if err != nil {
+		return nil, err
+	}
+	if err := CliPrepare(stmt, nq.SQL); err != nil {
+		return nil, err
+	}
+	return nq, nil
+}
+
+// CliBindNamed binds the values of arg to the placeholders of nq by CliBind(), see NamedQuery.Args() for arg.
+func CliBindNamed(stmt unsafe.Pointer, nq *NamedQuery, arg any) error {
+	args, err := nq.Args(arg)
+	if err != nil {
+		return err
+	}
+	for i, v := range args {
+		if err := CliBind(stmt, i, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// driverQuery is the query of a database/sql statement.
+// The `:name` and `@name` placeholders are rewritten only if the statement is executed
+// with sql.Named() arguments, otherwise the query is prepared as it is,
+// since `:` and `@` can be a part of the query (e.g. the identifiers and the literals).
+type driverQuery struct {
+	query    string
+	named    *NamedQuery // nil if the query has no named placeholder
+	namedErr error       // the error of ParseNamed()
+	prepared string      // the query that the statement is prepared with, empty if not prepared
+}
+
+func newDriverQuery(query string) *driverQuery {
+	ret := &driverQuery{query: query}
+	if nq, err := ParseNamed(query); err != nil {
+		ret.namedErr = err
+	} else if len(nq.Names) > 0 {
+		ret.named = nq
+	}
+	return ret
+}
+
+// deferred returns true if the query can not be prepared until the arguments are known.
+func (dq *driverQuery) deferred() bool {
+	return dq.named != nil || dq.namedErr != nil
+}
+
+// resolve returns the query to prepare for args, and args in the order of the placeholders.
+func (dq *driverQuery) resolve(args []driver.NamedValue) (string, []driver.NamedValue, error) {
+	if !hasNamedArgs(args) {
+		return dq.query, args, nil
+	}
+	if dq.namedErr != nil {
+		return "", nil, dq.namedErr
+	}
+	if dq.named == nil {
+		args, err := namedDriverArgs(nil, args)
+		return dq.query, args, err
+	}
+	args, err := namedDriverArgs(dq.named.Names, args)
+	return dq.named.SQL, args, err
+}
+
+func hasNamedArgs(args []driver.NamedValue) bool {
+	for _, arg := range args {
+		if arg.Name != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// namedDriverArgs orders the database/sql arguments by names,
+// args are used as they are if none of them has a name.
+func namedDriverArgs(names []string, args []driver.NamedValue) ([]driver.NamedValue, error) {
+	if !hasNamedArgs(args) {
+		return args, nil
+	}
+	values := map[string]any{}
+	for _, arg := range args {
+		if arg.Name == "" {
+			return nil, ErrNamedParamMixed()
+		}
+		values[arg.Name] = arg.Value
+	}
+	ret := make([]driver.NamedValue, len(names))
+	for i, name := range names {
+		v, ok := values[name]
+		if !ok {
+			return nil, ErrNamedParamMissing(name)
+		}
+		ret[i] = driver.NamedValue{Ordinal: i + 1, Value: v}
+	}
+	return ret, nil
+}
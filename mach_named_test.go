@@ -0,0 +1,131 @@
+package mach_test
+
+import (
+	"fmt"
+	"testing"
+	"unsafe"
+
+	mach "github.com/machbase/neo-engine/v8"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseNamed(t *testing.T) {
+	tests := []struct {
+		query string
+		sql   string
+		names []string
+	}{
+		{
+			query: `insert into t values(:name, :time, @value)`,
+			sql:   `insert into t values(?, ?, ?)`,
+			names: []string{"name", "time", "value"},
+		},
+		{
+			query: `select * from t where name = :name or alias = :name`,
+			sql:   `select * from t where name = ? or alias = ?`,
+			names: []string{"name", "name"},
+		},
+		{
+			query: `select ':skip', 'it''s :skip', "col:skip" from t where a = :a_1`,
+			sql:   `select ':skip', 'it''s :skip', "col:skip" from t where a = ?`,
+			names: []string{"a_1"},
+		},
+		{
+			query: "select a -- :skip\nfrom t /* @skip */ where b = @b",
+			sql:   "select a -- :skip\nfrom t /* @skip */ where b = ?",
+			names: []string{"b"},
+		},
+		{
+			query: `select a::varchar, b : c, '@' from t where id = ?`,
+			sql:   `select a::varchar, b : c, '@' from t where id = ?`,
+		},
+		{
+			query: `select 'unterminated :x`,
+			sql:   `select 'unterminated :x`,
+		},
+	}
+	for _, tt := range tests {
+		nq, err := mach.ParseNamed(tt.query)
+		require.NoError(t, err, tt.query)
+		require.Equal(t, tt.sql, nq.SQL, tt.query)
+		require.Equal(t, tt.names, nq.Names, tt.query)
+	}
+
+	_, err := mach.ParseNamed(`select * from t where a = :a and b = ?`)
+	require.Error(t, err)
+}
+
+func TestNamedArgs(t *testing.T) {
+	nq, err := mach.ParseNamed(`insert into t values(:name, :value, :name, @time)`)
+	require.NoError(t, err)
+
+	args, err := nq.Args(map[string]any{"name": "n1", "value": 1.5, "time": int64(10)})
+	require.NoError(t, err)
+	require.Equal(t, []any{"n1", 1.5, "n1", int64(10)}, args)
+
+	_, err = nq.Args(map[string]any{"name": "n1"})
+	require.Error(t, err)
+
+	type Row struct {
+		Name    string
+		Value   float64 `db:"value"`
+		Created int64   `db:"time"`
+		Ignored string  `db:"-"`
+		hidden  string
+	}
+	row := Row{Name: "n2", Value: 2.5, Created: 20}
+	args, err = nq.Args(row)
+	require.NoError(t, err)
+	require.Equal(t, []any{"n2", 2.5, "n2", int64(20)}, args)
+	args, err = nq.Args(&row)
+	require.NoError(t, err)
+	require.Equal(t, []any{"n2", 2.5, "n2", int64(20)}, args)
+
+	_, err = nq.Args(1)
+	require.Error(t, err)
+}
+
+func TestCliBindNamed(t *testing.T) {
+	var conn unsafe.Pointer
+	err := mach.CliConnect(global.CliEnv, fmt.Sprintf("SERVER=127.0.0.1;UID=SYS;PWD=MANAGER;CONNTYPE=1;PORT_NO=%d", machPort), &conn)
+	require.NoError(t, err)
+	defer mach.CliDisconnect(conn)
+	require.NoError(t, mach.CliExecDirectConn(conn, `create table cli_named (name varchar(20), value double)`))
+	defer mach.CliExecDirectConn(conn, `drop table cli_named`)
+
+	var stmt unsafe.Pointer
+	require.NoError(t, mach.CliAllocStmt(conn, &stmt))
+	defer mach.CliFreeStmt(stmt)
+	nq, err := mach.CliPrepareNamed(stmt, `insert into cli_named values(:name, :value)`)
+	require.NoError(t, err)
+
+	require.NoError(t, mach.CliBindNamed(stmt, nq, map[string]any{"name": "map", "value": 1.5}))
+	require.NoError(t, mach.CliExecute(stmt))
+	require.NoError(t, mach.CliExecuteClean(stmt))
+	type Row struct {
+		Name  string
+		Value float64
+	}
+	require.NoError(t, mach.CliBindNamed(stmt, nq, &Row{Name: "struct", Value: 2.5}))
+	require.NoError(t, mach.CliExecute(stmt))
+	require.NoError(t, mach.CliExecuteClean(stmt))
+	require.Error(t, mach.CliBindNamed(stmt, nq, map[string]any{"name": "missing"}))
+	require.NoError(t, mach.CliExecDirectConn(conn, `EXEC table_flush(cli_named)`))
+
+	nq, err = mach.CliPrepareNamed(stmt, `select count(*), sum(value) from cli_named where name = :a or name = :b`)
+	require.NoError(t, err)
+	require.NoError(t, mach.CliBindNamed(stmt, nq, map[string]any{"a": "map", "b": "struct"}))
+	require.NoError(t, mach.CliExecute(stmt))
+	end, err := mach.CliFetch(stmt)
+	require.NoError(t, err)
+	require.False(t, end)
+	var count int64
+	var sum float64
+	_, err = mach.CliGetData(stmt, 0, mach.MACHCLI_C_TYPE_INT64, unsafe.Pointer(&count), 8)
+	require.NoError(t, err)
+	_, err = mach.CliGetData(stmt, 1, mach.MACHCLI_C_TYPE_DOUBLE, unsafe.Pointer(&sum), 8)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), count)
+	require.Equal(t, 4.0, sum)
+	require.NoError(t, mach.CliExecuteClean(stmt))
+}
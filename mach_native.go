@@ -1,6 +1,7 @@
 package mach
 
 import (
+	"bytes"
 	"fmt"
 	"net"
 	"strings"
@@ -8,8 +9,6 @@ import (
 	"sync/atomic"
 	"time"
 	"unsafe"
-
-	"github.com/machbase/neo-engine/v8/native"
 )
 
 /*
@@ -244,21 +243,57 @@ func EngUserAuth(envHandle unsafe.Pointer, username string, password string) (bo
 	}
 }
 
+// EngExplain returns the plan of the prepared statement,
+// the buffer grows until the whole plan fits in it.
 func EngExplain(stmt unsafe.Pointer, full bool) (string, error) {
-	var cstr = [1024 * 16]C.char{}
 	var mode = 0
 	if full {
 		mode = 1
 	}
-	if rt := C.MachExplain(stmt, &cstr[0], C.int(len(cstr)), C.int(mode)); rt != 0 {
-		stmtErr := EngError(stmt)
-		if stmtErr != nil {
-			return "", stmtErr
-		} else {
-			return "", ErrDatabaseReturns("MachExplain", int(rt))
+	return explainGrow(func(buf *C.char, bufLen int) error {
+		if rt := C.MachExplain(stmt, buf, C.int(bufLen), C.int(mode)); rt != 0 {
+			stmtErr := EngError(stmt)
+			if stmtErr != nil {
+				return stmtErr
+			} else {
+				return ErrDatabaseReturns("MachExplain", int(rt))
+			}
+		}
+		return nil
+	})
+}
+
+const (
+	explainBufferSize    = 16 * 1024        // initial size of the explain buffer
+	explainBufferMaxSize = 64 * 1024 * 1024 // the plan is cut at this size
+)
+
+// explainGrow calls fn with the buffer that doubles while the plan fills it up.
+// The engine may fail instead of cutting the plan if the buffer is too small,
+// so the error is retried with the larger buffer only if the plan filled the buffer,
+// the other errors are returned at the first call.
+func explainGrow(fn func(buf *C.char, bufLen int) error) (string, error) {
+	for size := explainBufferSize; ; size *= 2 {
+		buf := C.calloc(1, C.size_t(size))
+		err := fn((*C.char)(buf), size)
+		n := bytes.IndexByte(unsafe.Slice((*byte)(buf), size), 0)
+		if n < 0 {
+			n = size
+		}
+		// the plan may be cut if it reaches the end of the buffer
+		filled := n >= size-1
+		if filled && size < explainBufferMaxSize {
+			C.free(buf)
+			continue
+		}
+		if err != nil {
+			C.free(buf)
+			return "", err
 		}
+		ret := C.GoStringN((*C.char)(buf), C.int(n))
+		C.free(buf)
+		return ret, nil
 	}
-	return C.GoString(&cstr[0]), nil
 }
 
 func EngAllocStmt(conn unsafe.Pointer, stmt *unsafe.Pointer) error {
@@ -278,6 +313,7 @@ func EngAllocStmt(conn unsafe.Pointer, stmt *unsafe.Pointer) error {
 
 func EngFreeStmt(stmt unsafe.Pointer) error {
 	metricEngStmt.Add(-1)
+	EngSetParamDescs(stmt, nil)
 	if rt := C.MachFreeStmt(stmt); rt != 0 {
 		stmtErr := EngError(stmt)
 		if stmtErr != nil {
@@ -290,6 +326,8 @@ func EngFreeStmt(stmt unsafe.Pointer) error {
 }
 
 func EngPrepare(stmt unsafe.Pointer, sqlText string) error {
+	// the parameters of the previous query
+	EngSetParamDescs(stmt, nil)
 	cstr := C.CString(sqlText)
 	defer C.free(unsafe.Pointer(cstr))
 	if rt := C.MachPrepare(stmt, cstr); rt != 0 {
@@ -328,6 +366,7 @@ func EngExecuteClean(stmt unsafe.Pointer) error {
 }
 
 func EngDirectExecute(stmt unsafe.Pointer, sqlText string) error {
+	EngSetParamDescs(stmt, nil)
 	cstr := C.CString(sqlText)
 	defer C.free(unsafe.Pointer(cstr))
 	if rt := C.MachDirectExecute(stmt, cstr); rt != 0 {
@@ -1131,12 +1170,15 @@ func CliInitialize(env *unsafe.Pointer) error {
 		return ErrDatabaseReturns("MachCLIInitialize", int(rt))
 	}
 	*env = tmpEnv
-	native.InitSignalHandler()
+	registerCliEnv(tmpEnv)
 	return nil
 }
 
+// CliFinalize returns error without finalizing if env is not from CliInitialize() or finalized already.
 func CliFinalize(env unsafe.Pointer) error {
-	native.DeinitSignalHandler()
+	if err := unregisterCliEnv(env); err != nil {
+		return err
+	}
 	if rt := C.MachCLIFinalize(env); rt != 0 {
 		return ErrDatabaseReturns("MachCLIFinalize", int(rt))
 	}
@@ -1200,6 +1242,8 @@ func CliAllocStmt(conn unsafe.Pointer, stmt *unsafe.Pointer) error {
 }
 
 func CliFreeStmt(stmt unsafe.Pointer) error {
+	// the memory of the bound values is not used after this even if it fails
+	defer cliBindRelease(stmt)
 	if rt := C.MachCLIFreeStmt(stmt); rt != 0 {
 		return CliErrorCaller(stmt, MACHCLI_HANDLE_STMT, "MachCLIFreeStmt()")
 	}
@@ -1232,6 +1276,7 @@ func CliExecDirect(stmt unsafe.Pointer, query string) error {
 }
 
 func CliExecuteClean(stmt unsafe.Pointer) error {
+	defer cliBindRelease(stmt)
 	if rt := C.MachCLIExecuteClean(stmt); rt != 0 {
 		return CliErrorCaller(stmt, MACHCLI_HANDLE_STMT, "MachCLIExecuteClean()")
 	}
@@ -1305,47 +1350,56 @@ const (
 	MACHCLI_C_TYPE_BINARY CType = 107
 )
 
-// * DDL: 1-255
-// * ALTER SYSTEM: 256-511
-// * SELECT: 512
-// * INSERT: 513
-// * DELETE: 514-518
-// * INSERT_SELECT: 519
-// * UPDATE: 520
-// * EXEC_ROLLUP: 522-524
-
+// StmtType is the statement type of CliGetStmtType(), see CliStmtKind() for the numbering.
+// The type of EngStmtType() should be classified by EngStmtKind().
 type StmtType int
 
+func (typ StmtType) Kind() StmtKind {
+	return CliStmtKind(int(typ))
+}
+
 func (typ StmtType) IsSelect() bool {
-	return typ == 512
+	return typ.Kind() == StmtKindSelect
 }
 
 func (typ StmtType) IsDDL() bool {
-	return typ >= 1 && typ <= 255
+	return typ.Kind() == StmtKindDDL
 }
 
 func (typ StmtType) IsAlterSystem() bool {
-	return typ >= 256 && typ <= 511
+	return typ.Kind() == StmtKindAlterSystem
 }
 
 func (typ StmtType) IsInsert() bool {
-	return typ == 513
+	return typ.Kind() == StmtKindInsert
 }
 
 func (typ StmtType) IsDelete() bool {
-	return typ >= 514 && typ <= 518
+	return typ.Kind() == StmtKindDelete
 }
 
 func (typ StmtType) IsInsertSelect() bool {
-	return typ == 519
+	return typ.Kind() == StmtKindInsertSelect
 }
 
 func (typ StmtType) IsUpdate() bool {
-	return typ == 520
+	return typ.Kind() == StmtKindUpdate
 }
 
 func (typ StmtType) IsExecRollup() bool {
-	return typ >= 522 && typ <= 524
+	return typ.Kind() == StmtKindExecRollup
+}
+
+// CliExplain returns the plan of the query, the buffer grows until the whole plan fits in it.
+func CliExplain(stmt unsafe.Pointer, query string) (string, error) {
+	cstr := C.CString(query)
+	defer C.free(unsafe.Pointer(cstr))
+	return explainGrow(func(buf *C.char, bufLen int) error {
+		if rt := C.MachCLIExplain(stmt, cstr, buf, C.int(bufLen)); rt != 0 {
+			return CliErrorCaller(stmt, MACHCLI_HANDLE_STMT, "MachCLIExplain()")
+		}
+		return nil
+	})
 }
 
 func CliGetStmtType(stmt unsafe.Pointer) (int, error) {
@@ -1387,6 +1441,15 @@ func CliBindParam(stmt unsafe.Pointer, paramNo int, cType CType, sqlType SqlType
 	return nil
 }
 
+// cMalloc allocates size bytes of C memory filled with zero.
+func cMalloc(size int) unsafe.Pointer {
+	return C.calloc(1, C.size_t(size))
+}
+
+func cFree(ptr unsafe.Pointer) {
+	C.free(ptr)
+}
+
 type CliParamDesc struct {
 	Type      SqlType
 	Precision int
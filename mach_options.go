@@ -0,0 +1,120 @@
+package mach
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// InitFlag is the option bits of EngInitialize(), see MACH_OPT_XXX in machEngine.h
+type InitFlag int
+
+const (
+	MACH_OPT_NONE            InitFlag = 0
+	MACH_OPT_SIGHANDLER_MASK InitFlag = 3
+	MACH_OPT_SIGHANDLER_ON   InitFlag = 0
+	MACH_OPT_SIGHANDLER_OFF  InitFlag = 1
+	MACH_OPT_SIGINT_OFF      InitFlag = 2
+)
+
+// SignalHandler returns true if the native signal handler is installed.
+func (f InitFlag) SignalHandler() bool {
+	return f&MACH_OPT_SIGHANDLER_MASK != MACH_OPT_SIGHANDLER_OFF
+}
+
+// SigInt returns true if the native signal handler takes SIGINT.
+func (f InitFlag) SigInt() bool {
+	return f&MACH_OPT_SIGHANDLER_MASK == MACH_OPT_SIGHANDLER_ON
+}
+
+type InitOption func(*InitFlag)
+
+// WithSignalHandler turns the native signal handler on or off.
+// Turn it off to receive all signals by os/signal.
+func WithSignalHandler(on bool) InitOption {
+	return func(f *InitFlag) {
+		if on {
+			*f = (*f &^ MACH_OPT_SIGHANDLER_MASK) | MACH_OPT_SIGHANDLER_ON
+		} else {
+			*f = (*f &^ MACH_OPT_SIGHANDLER_MASK) | MACH_OPT_SIGHANDLER_OFF
+		}
+	}
+}
+
+// WithSigIntDisabled keeps the native signal handler except for SIGINT.
+func WithSigIntDisabled() InitOption {
+	return func(f *InitFlag) {
+		*f = (*f &^ MACH_OPT_SIGHANDLER_MASK) | MACH_OPT_SIGINT_OFF
+	}
+}
+
+// WithInitFlag sets the raw flag bits.
+func WithInitFlag(flag InitFlag) InitOption {
+	return func(f *InitFlag) {
+		*f = flag
+	}
+}
+
+// InitFlags returns the flag that opts make.
+func InitFlags(opts ...InitOption) InitFlag {
+	ret := MACH_OPT_NONE
+	for _, o := range opts {
+		o(&ret)
+	}
+	return ret
+}
+
+type ShutdownResult struct {
+	Signal os.Signal
+	Report *ShutdownReport
+	Err    error
+}
+
+// ShutdownOnSignal calls GracefulShutdown() with the drain timeout when one of sigs arrives.
+// The result is sent to the returned channel.
+// If ctx ends before a signal, it stops listening and closes the channel.
+//
+// The env should be initialized with WithSignalHandler(false) or WithSigIntDisabled(),
+// otherwise the native handler takes SIGINT before os/signal.
+// If sigs is empty, it is os.Interrupt and SIGTERM with WithSignalHandler(false),
+// and only os.Interrupt with WithSigIntDisabled() since the native handler keeps the others.
+func (env *Env) ShutdownOnSignal(ctx context.Context, drain time.Duration, sigs ...os.Signal) (<-chan ShutdownResult, error) {
+	if env.flag.SigInt() {
+		return nil, ErrEnvSignalHandlerOn(env.flag)
+	}
+	if len(sigs) == 0 {
+		sigs = DefaultShutdownSignals(env.flag)
+	}
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, sigs...)
+	ret := make(chan ShutdownResult, 1)
+	go func() {
+		defer close(ret)
+		defer signal.Stop(sigCh)
+		select {
+		case <-ctx.Done():
+			return
+		case sig := <-sigCh:
+			drainCtx, cancel := context.WithTimeout(context.Background(), drain)
+			report, err := env.GracefulShutdown(drainCtx)
+			cancel()
+			ret <- ShutdownResult{Signal: sig, Report: report, Err: err}
+		}
+	}()
+	return ret, nil
+}
+
+// DefaultShutdownSignals returns the signals that ShutdownOnSignal() listens by default,
+// the ones that the native signal handler of the flag does not take.
+func DefaultShutdownSignals(flag InitFlag) []os.Signal {
+	switch {
+	case flag.SigInt():
+		return nil
+	case flag.SignalHandler():
+		return []os.Signal{os.Interrupt}
+	default:
+		return []os.Signal{os.Interrupt, syscall.SIGTERM}
+	}
+}
@@ -0,0 +1,85 @@
+package mach_test
+
+import (
+	"context"
+	"os"
+	"runtime"
+	"syscall"
+	"testing"
+	"time"
+
+	mach "github.com/machbase/neo-engine/v8"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInitFlags(t *testing.T) {
+	tests := []struct {
+		opts          []mach.InitOption
+		expect        mach.InitFlag
+		signalHandler bool
+		sigInt        bool
+	}{
+		{nil, mach.MACH_OPT_NONE, true, true},
+		{[]mach.InitOption{mach.WithSignalHandler(true)}, mach.MACH_OPT_SIGHANDLER_ON, true, true},
+		{[]mach.InitOption{mach.WithSignalHandler(false)}, mach.MACH_OPT_SIGHANDLER_OFF, false, false},
+		{[]mach.InitOption{mach.WithSigIntDisabled()}, mach.MACH_OPT_SIGINT_OFF, true, false},
+		{[]mach.InitOption{mach.WithSignalHandler(false), mach.WithSigIntDisabled()}, mach.MACH_OPT_SIGINT_OFF, true, false},
+		{[]mach.InitOption{mach.WithSigIntDisabled(), mach.WithSignalHandler(true)}, mach.MACH_OPT_SIGHANDLER_ON, true, true},
+		{[]mach.InitOption{mach.WithInitFlag(mach.MACH_OPT_SIGHANDLER_OFF)}, mach.MACH_OPT_SIGHANDLER_OFF, false, false},
+	}
+	for i, tc := range tests {
+		flag := mach.InitFlags(tc.opts...)
+		require.Equal(t, tc.expect, flag, "case %d", i)
+		require.Equal(t, tc.signalHandler, flag.SignalHandler(), "case %d", i)
+		require.Equal(t, tc.sigInt, flag.SigInt(), "case %d", i)
+	}
+}
+
+func TestShutdownOnSignal(t *testing.T) {
+	// the test env is initialized with the native handler that takes SIGINT
+	if global.Env.Flag().SigInt() {
+		_, err := global.Env.ShutdownOnSignal(context.Background(), time.Second)
+		require.Error(t, err)
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	ch, err := global.Env.ShutdownOnSignal(ctx, time.Second)
+	require.NoError(t, err)
+	cancel()
+	_, ok := <-ch
+	require.False(t, ok)
+}
+
+func TestShutdownOnSignalDelivered(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("os.Process.Signal() can not send SIGHUP on windows")
+	}
+	require.Equal(t, []os.Signal{os.Interrupt, syscall.SIGTERM}, mach.DefaultShutdownSignals(mach.MACH_OPT_SIGHANDLER_OFF))
+	require.Equal(t, []os.Signal{os.Interrupt}, mach.DefaultShutdownSignals(mach.MACH_OPT_SIGINT_OFF))
+	require.Empty(t, mach.DefaultShutdownSignals(mach.MACH_OPT_SIGHANDLER_ON))
+
+	// an env of its own, the database is not started so that the shutdown reports the state
+	home, err := mach.TempHome(nil, mach.HomeMinFreeSpace(0))
+	require.NoError(t, err)
+	defer home.Close()
+	env, err := mach.NewEnv(home.Dir, machPort+10, mach.WithSignalHandler(false))
+	require.NoError(t, err)
+	defer env.Close()
+	ch, err := env.ShutdownOnSignal(context.Background(), time.Second, syscall.SIGHUP)
+	require.NoError(t, err)
+
+	proc, err := os.FindProcess(os.Getpid())
+	require.NoError(t, err)
+	require.NoError(t, proc.Signal(syscall.SIGHUP))
+	select {
+	case result := <-ch:
+		require.Equal(t, syscall.SIGHUP, result.Signal)
+		// GracefulShutdown() ran and rejected the env that is not started
+		require.Error(t, result.Err)
+		require.Equal(t, mach.EnvStateInitialized, env.State())
+	case <-time.After(5 * time.Second):
+		t.Fatal("shutdown is not called")
+	}
+	_, ok := <-ch
+	require.False(t, ok)
+}
@@ -0,0 +1,206 @@
+package mach
+
+import (
+	"context"
+	"slices"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultPoolMaxIdle is the max idle connections of Pool when PoolOptions.MaxIdle is 0.
+const DefaultPoolMaxIdle = 2
+
+// PoolPingQuery is the query of the default health check of Pool.
+var PoolPingQuery = "select count(*) from m$sys_users"
+
+type PoolOptions struct {
+	// User of the connections, the connections are made by ConnectTrust() if Password is empty.
+	User     string
+	Password string
+	// MaxOpen limits the connections that are open, 0 means unlimited.
+	MaxOpen int
+	// MaxIdle limits the idle connections, 0 means DefaultPoolMaxIdle and negative means no idle connection.
+	MaxIdle int
+	// IdleTimeout closes the connections that are idle longer than it, 0 means no timeout.
+	IdleTimeout time.Duration
+	// MaxLifetime closes the connections that are older than it, 0 means no limit.
+	MaxLifetime time.Duration
+	// HealthCheck is called on an idle connection before it is checked out,
+	// the connection is closed if it returns error. Nil means pinging with PoolPingQuery.
+	HealthCheck func(*Conn) error
+}
+
+// PoolStats is the counters of Pool.
+type PoolStats struct {
+	MaxOpen int `json:"maxOpen"`
+	Open    int `json:"open"`
+	InUse   int `json:"inUse"`
+	Idle    int `json:"idle"`
+	// session ids of the open connections
+	Sessions []uint64 `json:"sessions"`
+
+	Hits         int64         `json:"hits"`
+	Misses       int64         `json:"misses"`
+	WaitCount    int64         `json:"waitCount"`
+	WaitDuration time.Duration `json:"waitDuration"`
+
+	HealthCheckFailed int64 `json:"healthCheckFailed"`
+	MaxIdleClosed     int64 `json:"maxIdleClosed"`
+	IdleTimeoutClosed int64 `json:"idleTimeoutClosed"`
+	LifetimeClosed    int64 `json:"lifetimeClosed"`
+	// connections that returned a network error, only by CliPool
+	NetworkErrorClosed int64 `json:"networkErrorClosed,omitempty"`
+}
+
+// Pool keeps the engine connections of Env for reuse.
+type Pool struct {
+	env  *Env
+	opts PoolOptions
+	core *poolCore[*PoolConn]
+	// envClosed is set if the env is closed, the connections are already closed by it
+	envClosed atomic.Bool
+}
+
+// PoolConn is a connection that is checked out from Pool,
+// it should be returned by Release().
+type PoolConn struct {
+	*Conn
+	poolItem
+	pool *Pool
+}
+
+// NewPool creates a connection pool of the env.
+// The pool is closed when Close() is called, it does not close the env.
+func (env *Env) NewPool(opts PoolOptions) *Pool {
+	if opts.HealthCheck == nil {
+		opts.HealthCheck = pingConn
+	}
+	ret := &Pool{env: env, opts: opts}
+	ret.core = newPoolCore(poolConfig{
+		MaxOpen:     opts.MaxOpen,
+		MaxIdle:     opts.MaxIdle,
+		IdleTimeout: opts.IdleTimeout,
+		MaxLifetime: opts.MaxLifetime,
+	}, poolHooks[*PoolConn]{
+		connect:   ret.connect,
+		close:     ret.closeConn,
+		check:     func(pc *PoolConn) error { return opts.HealthCheck(pc.Conn) },
+		unusable:  func(pc *PoolConn) bool { return pc.Conn.isClosed() },
+		closedErr: ErrPoolClosed,
+	})
+	env.mu.Lock()
+	if env.pools == nil {
+		env.pools = map[*Pool]struct{}{}
+	}
+	env.pools[ret] = struct{}{}
+	env.mu.Unlock()
+	return ret
+}
+
+// Pools returns the pools of the env that are not closed yet.
+func (env *Env) Pools() []*Pool {
+	env.mu.Lock()
+	defer env.mu.Unlock()
+	ret := make([]*Pool, 0, len(env.pools))
+	for p := range env.pools {
+		ret = append(ret, p)
+	}
+	return ret
+}
+
+// Get checks out an idle connection or opens a new one.
+// If MaxOpen connections are open, it waits until one is released or ctx ends.
+func (pool *Pool) Get(ctx context.Context) (*PoolConn, error) {
+	return pool.core.get(ctx)
+}
+
+func (pool *Pool) connect() (*PoolConn, error) {
+	var conn *Conn
+	var err error
+	if pool.opts.Password == "" {
+		conn, err = pool.env.ConnectTrust(pool.opts.User)
+	} else {
+		conn, err = pool.env.Connect(pool.opts.User, pool.opts.Password)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &PoolConn{Conn: conn, pool: pool}, nil
+}
+
+// closeConn closes the connection that the pool discards.
+// If the env is closed, the connection is only flagged closed since the engine is gone.
+func (pool *Pool) closeConn(pc *PoolConn) {
+	if pool.envClosed.Load() {
+		pc.Conn.close()
+	} else {
+		pc.Conn.Close()
+	}
+}
+
+// Release returns the connection to the pool.
+// The connection is closed instead if the pool is closed or has enough idle connections.
+// It is safe to call Release() more than once.
+func (pc *PoolConn) Release() {
+	pc.pool.core.release(pc)
+}
+
+// Discard closes the connection instead of returning it to the pool,
+// it is for the connection that is in a bad state.
+func (pc *PoolConn) Discard() {
+	pc.pool.core.drop(pc)
+}
+
+func (pc *PoolConn) CreatedAt() time.Time {
+	return pc.createdAt
+}
+
+func (pool *Pool) Stats() PoolStats {
+	sessions := []uint64{}
+	ret := pool.core.snapshot(func(pc *PoolConn) {
+		sessions = append(sessions, pc.sessionID)
+	})
+	slices.Sort(sessions)
+	ret.Sessions = sessions
+	return ret
+}
+
+// Close closes the idle connections and the pool,
+// the connections in use are closed when they are released.
+func (pool *Pool) Close() error {
+	if !pool.shutdown(false) {
+		return nil
+	}
+	pool.env.mu.Lock()
+	delete(pool.env.pools, pool)
+	pool.env.mu.Unlock()
+	return nil
+}
+
+// shutdown marks the pool closed and closes the idle connections,
+// it returns false if the pool is already closed.
+// envClosed is true when Env.close() calls it, the connections in use
+// are closed without untracking from the env when they are released.
+func (pool *Pool) shutdown(envClosed bool) bool {
+	if envClosed {
+		pool.envClosed.Store(true)
+	}
+	return pool.core.shutdown()
+}
+
+func pingConn(conn *Conn) error {
+	stmt, err := conn.NewStmt()
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	if err := stmt.DirectExecute(PoolPingQuery); err != nil {
+		return err
+	}
+	if exists, err := stmt.Fetch(); err != nil {
+		return err
+	} else if !exists {
+		return ErrDatabaseNoRows(PoolPingQuery)
+	}
+	return nil
+}
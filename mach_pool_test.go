@@ -0,0 +1,98 @@
+package mach_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	mach "github.com/machbase/neo-engine/v8"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPool(t *testing.T) {
+	pool := global.Env.NewPool(mach.PoolOptions{User: "sys", MaxOpen: 2, MaxIdle: 1})
+	defer pool.Close()
+	ctx := context.Background()
+
+	c1, err := pool.Get(ctx)
+	require.NoError(t, err)
+	c2, err := pool.Get(ctx)
+	require.NoError(t, err)
+	require.NotEqual(t, c1.SessionID(), c2.SessionID())
+
+	// all slots are in use
+	timeoutCtx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	_, err = pool.Get(timeoutCtx)
+	cancel()
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	stats := pool.Stats()
+	require.Equal(t, 2, stats.Open)
+	require.Equal(t, 2, stats.InUse)
+	require.Equal(t, int64(1), stats.WaitCount)
+	require.ElementsMatch(t, []uint64{c1.SessionID(), c2.SessionID()}, stats.Sessions)
+
+	// c1 becomes idle, c2 is closed by MaxIdle
+	c1.Release()
+	c1.Release()
+	c2.Release()
+	stats = pool.Stats()
+	require.Equal(t, 1, stats.Open)
+	require.Equal(t, 1, stats.Idle)
+	require.Equal(t, int64(1), stats.MaxIdleClosed)
+
+	c3, err := pool.Get(ctx)
+	require.NoError(t, err)
+	require.Equal(t, c1.SessionID(), c3.SessionID())
+	require.Equal(t, int64(1), pool.Stats().Hits)
+
+	// a waiter takes the released connection
+	c4, err := pool.Get(ctx)
+	require.NoError(t, err)
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		c4.Release()
+	}()
+	c5, err := pool.Get(ctx)
+	require.NoError(t, err)
+	require.Equal(t, c4.SessionID(), c5.SessionID())
+	c3.Discard()
+	c5.Release()
+
+	require.NoError(t, pool.Close())
+	_, err = pool.Get(ctx)
+	require.Error(t, err)
+	require.NotContains(t, global.Env.Pools(), pool)
+}
+
+func TestPoolHealthCheck(t *testing.T) {
+	broken := false
+	pool := global.Env.NewPool(mach.PoolOptions{
+		User:        "sys",
+		IdleTimeout: 100 * time.Millisecond,
+		HealthCheck: func(c *mach.Conn) error {
+			if broken {
+				return errors.New("broken")
+			}
+			return nil
+		},
+	})
+	defer pool.Close()
+	ctx := context.Background()
+
+	c1, err := pool.Get(ctx)
+	require.NoError(t, err)
+	c1.Release()
+	broken = true
+	c2, err := pool.Get(ctx)
+	require.NoError(t, err)
+	require.NotEqual(t, c1.SessionID(), c2.SessionID())
+	require.Equal(t, int64(1), pool.Stats().HealthCheckFailed)
+	c2.Release()
+
+	require.Eventually(t, func() bool {
+		return pool.Stats().Open == 0
+	}, 2*time.Second, 50*time.Millisecond)
+	require.Equal(t, int64(1), pool.Stats().IdleTimeoutClosed)
+}
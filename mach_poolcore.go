@@ -0,0 +1,346 @@
+package mach
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// poolItem is the state of a pooled connection that poolCore keeps.
+type poolItem struct {
+	createdAt time.Time
+	idleAt    time.Time
+	released  bool
+	broken    bool // the connection returned a network error
+}
+
+func (it *poolItem) item() *poolItem {
+	return it
+}
+
+// pooled is the connection of poolCore, *PoolConn or *CliPoolConn.
+type pooled interface {
+	comparable
+	item() *poolItem
+}
+
+// poolConfig is the limits of poolCore.
+type poolConfig struct {
+	MaxOpen     int
+	MaxIdle     int
+	IdleTimeout time.Duration
+	MaxLifetime time.Duration
+}
+
+// poolHooks connects the pool logic to the engine or the CLI.
+type poolHooks[P pooled] struct {
+	// connect opens a new connection
+	connect func() (P, error)
+	// close closes the connection
+	close func(P)
+	// check validates an idle connection before it is checked out,
+	// it sets broken of the item if the error is of the network.
+	check func(P) error
+	// unusable returns true if the connection is closed by others, it can be nil
+	unusable func(P) bool
+	// closedErr is the error of Get() on the closed pool
+	closedErr func() error
+}
+
+// poolCore is the connection pool logic that Pool and CliPool share.
+type poolCore[P pooled] struct {
+	conf  poolConfig
+	hooks poolHooks[P]
+
+	mu     sync.Mutex
+	sem    chan struct{} // a slot for every open connection, nil if MaxOpen is unlimited
+	idle   []P
+	open   map[P]struct{}
+	stats  PoolStats
+	closed bool
+	stop   chan struct{}
+	// returned is signaled when a connection becomes idle
+	returned chan struct{}
+}
+
+func newPoolCore[P pooled](conf poolConfig, hooks poolHooks[P]) *poolCore[P] {
+	if conf.MaxIdle == 0 {
+		conf.MaxIdle = DefaultPoolMaxIdle
+	}
+	if conf.MaxOpen > 0 && conf.MaxIdle > conf.MaxOpen {
+		conf.MaxIdle = conf.MaxOpen
+	}
+	ret := &poolCore[P]{
+		conf:     conf,
+		hooks:    hooks,
+		open:     map[P]struct{}{},
+		stop:     make(chan struct{}),
+		returned: make(chan struct{}, 1),
+	}
+	if conf.MaxOpen > 0 {
+		ret.sem = make(chan struct{}, conf.MaxOpen)
+	}
+	if interval := reapInterval(conf.IdleTimeout, conf.MaxLifetime); interval > 0 {
+		go ret.reaper(interval)
+	}
+	return ret
+}
+
+// get checks out an idle connection or opens a new one.
+// If MaxOpen connections are open, it waits until one is released or ctx ends.
+func (pool *poolCore[P]) get(ctx context.Context) (P, error) {
+	var zero P
+	var waitStarted time.Time
+	defer func() {
+		if !waitStarted.IsZero() {
+			pool.mu.Lock()
+			pool.stats.WaitCount++
+			pool.stats.WaitDuration += time.Since(waitStarted)
+			pool.mu.Unlock()
+		}
+	}()
+	for {
+		pool.mu.Lock()
+		if pool.closed {
+			pool.mu.Unlock()
+			return zero, pool.hooks.closedErr()
+		}
+		if n := len(pool.idle); n > 0 {
+			pc := pool.idle[n-1]
+			pool.idle = pool.idle[:n-1]
+			expired := pool.expired(pc, time.Now())
+			if n > 1 {
+				pool.signal()
+			}
+			pool.mu.Unlock()
+			if expired {
+				pool.discard(pc, &pool.stats.LifetimeClosed)
+				continue
+			}
+			if err := pool.hooks.check(pc); err != nil {
+				if pc.item().broken {
+					pool.discard(pc, &pool.stats.NetworkErrorClosed)
+				} else {
+					pool.discard(pc, &pool.stats.HealthCheckFailed)
+				}
+				continue
+			}
+			pool.mu.Lock()
+			pc.item().released = false
+			pool.stats.Hits++
+			pool.mu.Unlock()
+			return pc, nil
+		}
+		pool.mu.Unlock()
+
+		if pool.sem == nil {
+			return pool.connect()
+		}
+		select {
+		case pool.sem <- struct{}{}:
+			return pool.connect()
+		default:
+		}
+		if waitStarted.IsZero() {
+			waitStarted = time.Now()
+		}
+		select {
+		case pool.sem <- struct{}{}:
+			return pool.connect()
+		case <-pool.returned:
+		case <-pool.stop:
+			return zero, pool.hooks.closedErr()
+		case <-ctx.Done():
+			return zero, ctx.Err()
+		}
+	}
+}
+
+// connect opens a new connection, the caller should have taken the slot.
+func (pool *poolCore[P]) connect() (P, error) {
+	pool.mu.Lock()
+	pool.stats.Misses++
+	pool.mu.Unlock()
+	pc, err := pool.hooks.connect()
+	if err != nil {
+		pool.releaseSlot()
+		return pc, err
+	}
+	pc.item().createdAt = time.Now()
+	pool.mu.Lock()
+	pool.open[pc] = struct{}{}
+	pool.mu.Unlock()
+	return pc, nil
+}
+
+// signal wakes up a waiter of get() for the idle connection.
+func (pool *poolCore[P]) signal() {
+	select {
+	case pool.returned <- struct{}{}:
+	default:
+	}
+}
+
+func (pool *poolCore[P]) releaseSlot() {
+	if pool.sem != nil {
+		<-pool.sem
+	}
+}
+
+// expired returns true if the connection is over MaxLifetime.
+func (pool *poolCore[P]) expired(pc P, now time.Time) bool {
+	return pool.conf.MaxLifetime > 0 && now.Sub(pc.item().createdAt) >= pool.conf.MaxLifetime
+}
+
+// discard closes the connection that is taken out of the idle list and counts it.
+func (pool *poolCore[P]) discard(pc P, counter *int64) {
+	pool.mu.Lock()
+	delete(pool.open, pc)
+	if counter != nil {
+		*counter++
+	}
+	pool.mu.Unlock()
+	pool.hooks.close(pc)
+	pool.releaseSlot()
+}
+
+// release returns the connection to the pool.
+// The connection is closed instead if it is broken or closed by others,
+// or the pool is closed or has enough idle connections.
+func (pool *poolCore[P]) release(pc P) {
+	pool.mu.Lock()
+	it := pc.item()
+	if it.released {
+		pool.mu.Unlock()
+		return
+	}
+	it.released = true
+	now := time.Now()
+	var counter *int64
+	switch {
+	case pool.closed || (pool.hooks.unusable != nil && pool.hooks.unusable(pc)):
+	case it.broken:
+		counter = &pool.stats.NetworkErrorClosed
+	case len(pool.idle) >= pool.conf.MaxIdle:
+		counter = &pool.stats.MaxIdleClosed
+	case pool.expired(pc, now):
+		counter = &pool.stats.LifetimeClosed
+	default:
+		it.idleAt = now
+		pool.idle = append(pool.idle, pc)
+		// the connection keeps its slot while it is idle
+		pool.signal()
+		pool.mu.Unlock()
+		return
+	}
+	pool.mu.Unlock()
+	pool.discard(pc, counter)
+}
+
+// drop closes the connection instead of returning it to the pool.
+func (pool *poolCore[P]) drop(pc P) {
+	pool.mu.Lock()
+	it := pc.item()
+	if it.released {
+		pool.mu.Unlock()
+		return
+	}
+	it.released = true
+	pool.mu.Unlock()
+	pool.discard(pc, nil)
+}
+
+// markBroken flags the connection to be closed when it is released.
+func (pool *poolCore[P]) markBroken(pc P) {
+	pool.mu.Lock()
+	pc.item().broken = true
+	pool.mu.Unlock()
+}
+
+// snapshot returns the counters, fn is called for every open connection under the lock.
+func (pool *poolCore[P]) snapshot(fn func(P)) PoolStats {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+	ret := pool.stats
+	ret.MaxOpen = pool.conf.MaxOpen
+	ret.Open = len(pool.open)
+	ret.Idle = len(pool.idle)
+	ret.InUse = ret.Open - ret.Idle
+	if fn != nil {
+		for pc := range pool.open {
+			fn(pc)
+		}
+	}
+	return ret
+}
+
+// shutdown marks the pool closed and closes the idle connections,
+// the connections in use are closed when they are released.
+// It returns false if the pool is already closed.
+func (pool *poolCore[P]) shutdown() bool {
+	pool.mu.Lock()
+	if pool.closed {
+		pool.mu.Unlock()
+		return false
+	}
+	pool.closed = true
+	close(pool.stop)
+	idle := pool.idle
+	pool.idle = nil
+	pool.mu.Unlock()
+	for _, pc := range idle {
+		pool.discard(pc, nil)
+	}
+	return true
+}
+
+func (pool *poolCore[P]) reaper(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-pool.stop:
+			return
+		case <-ticker.C:
+			pool.reap()
+		}
+	}
+}
+
+// reap closes the idle connections that are over IdleTimeout or MaxLifetime.
+func (pool *poolCore[P]) reap() {
+	now := time.Now()
+	pool.mu.Lock()
+	var idleTimeout, lifetime []P
+	keep := pool.idle[:0]
+	for _, pc := range pool.idle {
+		if pool.expired(pc, now) {
+			lifetime = append(lifetime, pc)
+		} else if pool.conf.IdleTimeout > 0 && now.Sub(pc.item().idleAt) >= pool.conf.IdleTimeout {
+			idleTimeout = append(idleTimeout, pc)
+		} else {
+			keep = append(keep, pc)
+		}
+	}
+	pool.idle = keep
+	pool.mu.Unlock()
+
+	for _, pc := range idleTimeout {
+		pool.discard(pc, &pool.stats.IdleTimeoutClosed)
+	}
+	for _, pc := range lifetime {
+		pool.discard(pc, &pool.stats.LifetimeClosed)
+	}
+}
+
+func reapInterval(idleTimeout, maxLifetime time.Duration) time.Duration {
+	ret := idleTimeout
+	if maxLifetime > 0 && (ret == 0 || maxLifetime < ret) {
+		ret = maxLifetime
+	}
+	ret /= 2
+	if ret > 0 && ret < 10*time.Millisecond {
+		ret = 10 * time.Millisecond
+	}
+	return ret
+}
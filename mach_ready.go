@@ -0,0 +1,95 @@
+package mach
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"time"
+	"unsafe"
+)
+
+// ReadyCheckInterval is the interval of polling in WaitReady().
+var ReadyCheckInterval = 100 * time.Millisecond
+
+// ReadyCheckQuery is the query that WaitReady() runs to check the server is ready.
+var ReadyCheckQuery = "select count(*) from m$sys_users"
+
+const (
+	ReadyStageListener = "listener"
+	ReadyStageConnect  = "connect"
+	ReadyStageQuery    = "query"
+)
+
+// WaitReady blocks until the started database accepts connections on its port,
+// an EngConnectTrust() round-trip works and ReadyCheckQuery succeeds.
+// If ctx ends first, the returned error tells the stage that was not passed
+// and wraps both ctx.Err() and the last failure of the stage.
+func (env *Env) WaitReady(ctx context.Context) error {
+	if st := env.State(); st != EnvStateStarted {
+		return ErrEnvInvalidState("WaitReady", st)
+	}
+	addr := env.listenAddress()
+	ticker := time.NewTicker(ReadyCheckInterval)
+	defer ticker.Stop()
+	for {
+		stage, err := env.checkReady(ctx, addr)
+		if err == nil {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ErrEnvNotReady(stage, ctx.Err(), err)
+		case <-ticker.C:
+		}
+	}
+}
+
+// listenAddress returns "" if the port is unknown.
+func (env *Env) listenAddress() string {
+	port := int64(env.port)
+	host := "127.0.0.1"
+	if env.config != nil {
+		if port <= 0 {
+			port = env.config.PortNo
+		}
+		if ip := net.ParseIP(env.config.BindIPAddress); ip != nil && !ip.IsUnspecified() {
+			host = ip.String()
+		}
+	}
+	if port <= 0 {
+		return ""
+	}
+	return net.JoinHostPort(host, strconv.FormatInt(port, 10))
+}
+
+func (env *Env) checkReady(ctx context.Context, addr string) (string, error) {
+	if addr != "" {
+		dialer := net.Dialer{Timeout: time.Second}
+		c, err := dialer.DialContext(ctx, "tcp", addr)
+		if err != nil {
+			return ReadyStageListener, err
+		}
+		c.Close()
+	}
+
+	var conn unsafe.Pointer
+	if err := EngConnectTrust(env.Handle(), "sys", &conn); err != nil {
+		return ReadyStageConnect, err
+	}
+	defer EngDisconnect(conn)
+
+	var stmt unsafe.Pointer
+	if err := EngAllocStmt(conn, &stmt); err != nil {
+		return ReadyStageQuery, err
+	}
+	defer EngFreeStmt(stmt)
+	if err := EngDirectExecute(stmt, ReadyCheckQuery); err != nil {
+		return ReadyStageQuery, err
+	}
+	if next, err := EngFetch(stmt); err != nil {
+		return ReadyStageQuery, err
+	} else if !next {
+		return ReadyStageQuery, ErrDatabaseNoRows(ReadyCheckQuery)
+	}
+	return "", nil
+}
@@ -0,0 +1,111 @@
+package mach
+
+import (
+	"sort"
+	"sync"
+	"unsafe"
+
+	"github.com/machbase/neo-engine/v8/native"
+)
+
+// registry keeps the alive engine envs and CLI envs of the process.
+var registry = &envRegistry{
+	envs:    map[string]*Env{},
+	cliEnvs: map[unsafe.Pointer]struct{}{},
+}
+
+type envRegistry struct {
+	sync.Mutex
+	envs    map[string]*Env // key: absolute home path
+	cliEnvs map[unsafe.Pointer]struct{}
+}
+
+// lookup returns the env that uses homeDir or machPort, the caller should hold the lock.
+func (r *envRegistry) lookup(homeDir string, machPort int) *Env {
+	if env, ok := r.envs[homeDir]; ok {
+		return env
+	}
+	if machPort <= 0 {
+		return nil
+	}
+	for _, env := range r.envs {
+		if env.port == machPort {
+			return env
+		}
+	}
+	return nil
+}
+
+func unregisterEnv(env *Env) {
+	registry.Lock()
+	defer registry.Unlock()
+	if registry.envs[env.homeDir] == env {
+		delete(registry.envs, env.homeDir)
+	}
+}
+
+type EnvInfo struct {
+	HomeDir string   `json:"home_dir"`
+	Port    int      `json:"port"`
+	Flag    InitFlag `json:"flag"`
+	State   string   `json:"state"`
+	Refs    int      `json:"refs"`
+	Conns   int      `json:"conns"`
+}
+
+// Envs returns the engine envs that are alive in the process, sorted by the home path.
+func Envs() []EnvInfo {
+	registry.Lock()
+	envs := make([]*Env, 0, len(registry.envs))
+	for _, env := range registry.envs {
+		envs = append(envs, env)
+	}
+	registry.Unlock()
+
+	ret := make([]EnvInfo, 0, len(envs))
+	for _, env := range envs {
+		env.mu.Lock()
+		ret = append(ret, EnvInfo{
+			HomeDir: env.homeDir,
+			Port:    env.port,
+			Flag:    env.flag,
+			State:   env.state.String(),
+			Refs:    env.refs,
+			Conns:   len(env.conns),
+		})
+		env.mu.Unlock()
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i].HomeDir < ret[j].HomeDir })
+	return ret
+}
+
+// CliEnvCount returns the number of CLI envs that are initialized and not finalized.
+func CliEnvCount() int {
+	registry.Lock()
+	defer registry.Unlock()
+	return len(registry.cliEnvs)
+}
+
+// registerCliEnv installs the signal handler when the first CLI env is made.
+func registerCliEnv(env unsafe.Pointer) {
+	registry.Lock()
+	defer registry.Unlock()
+	if len(registry.cliEnvs) == 0 {
+		native.InitSignalHandler()
+	}
+	registry.cliEnvs[env] = struct{}{}
+}
+
+// unregisterCliEnv removes the signal handler when the last CLI env is finalized.
+func unregisterCliEnv(env unsafe.Pointer) error {
+	registry.Lock()
+	defer registry.Unlock()
+	if _, ok := registry.cliEnvs[env]; !ok {
+		return ErrCliEnvNotInitialized()
+	}
+	delete(registry.cliEnvs, env)
+	if len(registry.cliEnvs) == 0 {
+		native.DeinitSignalHandler()
+	}
+	return nil
+}
@@ -0,0 +1,344 @@
+package mach
+
+import (
+	"database/sql"
+	"math"
+	"net"
+	"reflect"
+	"strconv"
+	"time"
+	"unsafe"
+)
+
+// Rows is the result set of an executed engine or CLI statement.
+//
+//	for rows.Next() {
+//		if err := rows.Scan(&name, &ts, &value); err != nil {
+//			...
+//		}
+//	}
+//	if err := rows.Err(); err != nil {
+//		...
+//	}
+//
+// The statement is cleaned up when all the rows are read or Close() is called.
+type Rows struct {
+	columns []Column
+	fetch   func() (bool, error) // returns true if a row exists
+	value   func(idx int) (any, bool, error)
+	clean   func() error
+	row     bool
+	err     error
+	closed  bool
+}
+
+// EngRows returns the rows of the engine statement that is executed.
+func EngRows(stmt unsafe.Pointer) (*Rows, error) {
+	columns, err := EngColumns(stmt)
+	if err != nil {
+		return nil, err
+	}
+	return &Rows{
+		columns: columns,
+		fetch:   func() (bool, error) { return EngFetch(stmt) },
+		value:   func(idx int) (any, bool, error) { return engColumnValue(stmt, idx) },
+		clean:   func() error { return EngExecuteClean(stmt) },
+	}, nil
+}
+
+// Rows returns the rows of the statement that is executed.
+func (stmt *Stmt) Rows() (*Rows, error) {
+	columns, err := EngColumns(stmt.handle)
+	if err != nil {
+		return nil, err
+	}
+	return &Rows{
+		columns: columns,
+		fetch:   stmt.Fetch,
+		value:   func(idx int) (any, bool, error) { return engColumnValue(stmt.handle, idx) },
+		clean:   stmt.ExecuteClean,
+	}, nil
+}
+
+// CliRows returns the rows of the CLI statement that is executed.
+func CliRows(stmt unsafe.Pointer) (*Rows, error) {
+	columns, err := CliColumns(stmt)
+	if err != nil {
+		return nil, err
+	}
+	bufs := make([][]byte, len(columns))
+	return &Rows{
+		columns: columns,
+		fetch: func() (bool, error) {
+			end, err := CliFetch(stmt)
+			return !end, err
+		},
+		value: func(idx int) (any, bool, error) { return cliColumnValue(stmt, idx, columns[idx], &bufs[idx]) },
+		clean: func() error { return CliExecuteClean(stmt) },
+	}, nil
+}
+
+func (r *Rows) Columns() []Column {
+	return r.columns
+}
+
+// Next fetches the next row, it returns false at the end of the rows or on error.
+func (r *Rows) Next() bool {
+	if r.closed || r.err != nil {
+		return false
+	}
+	r.row, r.err = r.fetch()
+	if !r.row {
+		if err := r.Close(); r.err == nil {
+			r.err = err
+		}
+	}
+	return r.row
+}
+
+// Err returns the error that stopped Next().
+func (r *Rows) Err() error {
+	return r.err
+}
+
+// Close cleans up the statement, it is safe to call Close() more than once.
+func (r *Rows) Close() error {
+	if r.closed {
+		return nil
+	}
+	r.closed = true
+	r.row = false
+	return r.clean()
+}
+
+// Scan copies the columns of the current row into dest, a destination per column.
+// dest can be *any, sql.Scanner (e.g. sql.NullString) or a pointer to the Go types that the value
+// is converted into: the integer, float and bool types, string (IPs and datetimes are formatted),
+// []byte, time.Time (from datetimes and the integers in nanoseconds) and net.IP (from IPs and strings).
+// NULL is scanned into *any, sql.Scanner, a pointer to a pointer or []byte as nil,
+// the others return error.
+func (r *Rows) Scan(dest ...any) error {
+	if r.closed || !r.row {
+		return ErrScanNoRow()
+	}
+	if len(dest) != len(r.columns) {
+		return ErrScanColumnCount(len(r.columns), len(dest))
+	}
+	for idx, d := range dest {
+		v, valid, err := r.value(idx)
+		if err != nil {
+			return err
+		}
+		if !valid {
+			v = nil
+		}
+		if err := scanValue(idx, v, d); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var (
+	scanTimeType = reflect.TypeOf(time.Time{})
+	scanIPType   = reflect.TypeOf(net.IP{})
+)
+
+// scanValue converts the column value v into dest.
+func scanValue(idx int, v any, dest any) error {
+	switch d := dest.(type) {
+	case *any:
+		*d = v
+		return nil
+	case sql.Scanner:
+		return d.Scan(driverValue(v))
+	}
+	rv := reflect.ValueOf(dest)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return ErrScanInvalidDest(idx, dest)
+	}
+	elem := rv.Elem()
+	if v == nil {
+		switch elem.Kind() {
+		case reflect.Pointer, reflect.Interface, reflect.Slice, reflect.Map:
+			elem.SetZero()
+			return nil
+		}
+		return ErrScanNull(idx, dest)
+	}
+	if elem.Kind() == reflect.Pointer {
+		ptr := reflect.New(elem.Type().Elem())
+		if err := scanValue(idx, v, ptr.Interface()); err != nil {
+			return err
+		}
+		elem.Set(ptr)
+		return nil
+	}
+	if vv := reflect.ValueOf(v); vv.Type().AssignableTo(elem.Type()) {
+		elem.Set(vv)
+		return nil
+	}
+
+	ok := false
+	switch {
+	case elem.Type() == scanTimeType:
+		var n int64
+		if n, ok = scanInt64(v); ok {
+			elem.Set(reflect.ValueOf(time.Unix(0, n)))
+		}
+	case elem.Type() == scanIPType:
+		var ip net.IP
+		if s, isString := v.(string); isString {
+			if ip = net.ParseIP(s); ip != nil {
+				elem.Set(reflect.ValueOf(ip))
+				ok = true
+			}
+		}
+	}
+	if ok {
+		return nil
+	}
+	switch elem.Kind() {
+	case reflect.String:
+		var s string
+		if s, ok = scanString(v); ok {
+			elem.SetString(s)
+		}
+	case reflect.Slice:
+		if elem.Type().Elem().Kind() == reflect.Uint8 {
+			var s string
+			if s, ok = scanString(v); ok {
+				elem.SetBytes([]byte(s))
+			}
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		var n int64
+		if n, ok = scanInt64(v); ok && !elem.OverflowInt(n) {
+			elem.SetInt(n)
+		} else {
+			ok = false
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		var n uint64
+		if n, ok = scanUint64(v); ok && !elem.OverflowUint(n) {
+			elem.SetUint(n)
+		} else {
+			ok = false
+		}
+	case reflect.Float32, reflect.Float64:
+		var f float64
+		if f, ok = scanFloat64(v); ok && (elem.Kind() == reflect.Float64 || !elem.OverflowFloat(f)) {
+			elem.SetFloat(f)
+		} else {
+			ok = false
+		}
+	case reflect.Bool:
+		var b bool
+		if s, isString := v.(string); isString {
+			var err error
+			b, err = strconv.ParseBool(s)
+			ok = err == nil
+		} else {
+			var f float64
+			f, ok = scanFloat64(v)
+			b = f != 0
+		}
+		if ok {
+			elem.SetBool(b)
+		}
+	}
+	if !ok {
+		return ErrScanConvert(idx, v, dest)
+	}
+	return nil
+}
+
+// scanString formats the column value v.
+func scanString(v any) (string, bool) {
+	switch val := v.(type) {
+	case string:
+		return val, true
+	case []byte:
+		return string(val), true
+	case net.IP:
+		return val.String(), true
+	case time.Time:
+		return val.Format(time.RFC3339Nano), true
+	case float32:
+		return strconv.FormatFloat(float64(val), 'g', -1, 32), true
+	case float64:
+		return strconv.FormatFloat(val, 'g', -1, 64), true
+	case uint64:
+		return strconv.FormatUint(val, 10), true
+	}
+	if n, ok := scanInt64(v); ok {
+		return strconv.FormatInt(n, 10), true
+	}
+	return "", false
+}
+
+// scanInt64 converts the column value v of the integer types, the integral floats,
+// datetime in nanoseconds and the strings of the integers.
+func scanInt64(v any) (int64, bool) {
+	switch val := v.(type) {
+	case int16:
+		return int64(val), true
+	case int32:
+		return int64(val), true
+	case int64:
+		return val, true
+	case uint16:
+		return int64(val), true
+	case uint32:
+		return int64(val), true
+	case uint64:
+		return int64(val), val <= math.MaxInt64
+	case float32:
+		return scanInt64(float64(val))
+	case float64:
+		// float64(math.MaxInt64) rounds up to 2^63
+		return int64(val), val == math.Trunc(val) && val >= math.MinInt64 && val < math.MaxInt64
+	case time.Time:
+		return val.UnixNano(), true
+	case string:
+		n, err := strconv.ParseInt(val, 10, 64)
+		return n, err == nil
+	}
+	return 0, false
+}
+
+// scanUint64 is scanInt64 for the unsigned integers.
+func scanUint64(v any) (uint64, bool) {
+	switch val := v.(type) {
+	case uint64:
+		return val, true
+	case float32:
+		return scanUint64(float64(val))
+	case float64:
+		return uint64(val), val == math.Trunc(val) && val >= 0 && val < math.MaxUint64
+	case string:
+		n, err := strconv.ParseUint(val, 10, 64)
+		return n, err == nil
+	}
+	n, ok := scanInt64(v)
+	return uint64(n), ok && n >= 0
+}
+
+// scanFloat64 converts the column value v of the number types and the strings of the numbers.
+func scanFloat64(v any) (float64, bool) {
+	switch val := v.(type) {
+	case float32:
+		return float64(val), true
+	case float64:
+		return val, true
+	case uint64:
+		return float64(val), true
+	case string:
+		f, err := strconv.ParseFloat(val, 64)
+		return f, err == nil
+	case time.Time:
+		return 0, false
+	}
+	n, ok := scanInt64(v)
+	return float64(n), ok
+}
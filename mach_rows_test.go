@@ -0,0 +1,109 @@
+package mach_test
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+	"unsafe"
+
+	mach "github.com/machbase/neo-engine/v8"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRowsScan(t *testing.T) {
+	conn, err := global.Env.ConnectTrust("sys")
+	require.NoError(t, err)
+	defer conn.Close()
+	ctx := context.Background()
+
+	_, err = conn.ExecScript(ctx, `
+		create table rows_test (time datetime, short_value short, ulong_value ulong, float_value float,
+			double_value double, str_value varchar(20), ipv4_value ipv4, bin_value binary);
+	`, mach.BatchStopOnError)
+	require.NoError(t, err)
+	defer conn.ExecScript(ctx, `drop table rows_test;`, mach.BatchStopOnError)
+
+	now := time.Unix(0, time.Now().UnixNano()/1000*1000)
+	_, err = mach.Execute(ctx, conn, `insert into rows_test values(?, ?, ?, ?, ?, ?, ?, ?)`,
+		now, 1, uint64(2), float32(3.5), 4.5, "str", net.IPv4(192, 168, 0, 1), []byte{1, 2})
+	require.NoError(t, err)
+	_, err = mach.Execute(ctx, conn, `insert into rows_test values(?, ?, ?, ?, ?, ?, ?, ?)`,
+		now.Add(time.Second), nil, nil, nil, nil, nil, nil, nil)
+	require.NoError(t, err)
+	_, err = conn.ExecScript(ctx, `EXEC table_flush(rows_test)`, mach.BatchStopOnError)
+	require.NoError(t, err)
+
+	query := `select time, short_value, ulong_value, float_value, double_value, str_value, ipv4_value, bin_value
+		from rows_test order by time`
+	check := func(rows *mach.Rows) {
+		var ts time.Time
+		var tsStr string
+		var short int
+		var ulong sql.NullInt64
+		var flt float64
+		var dbl any
+		var str *string
+		var ip string
+		var bin []byte
+
+		require.True(t, rows.Next())
+		require.Len(t, rows.Columns(), 8)
+		require.Error(t, rows.Scan(&ts))
+		require.NoError(t, rows.Scan(&ts, &short, &ulong, &flt, &dbl, &str, &ip, &bin))
+		require.Equal(t, now.UnixNano(), ts.UnixNano())
+		require.Equal(t, 1, short)
+		require.Equal(t, sql.NullInt64{Int64: 2, Valid: true}, ulong)
+		require.Equal(t, 3.5, flt)
+		require.Equal(t, 4.5, dbl)
+		require.Equal(t, "str", *str)
+		require.Equal(t, "192.168.0.1", ip)
+		require.Equal(t, []byte{1, 2}, bin)
+		require.NoError(t, rows.Scan(&tsStr, &short, &ulong, &flt, &dbl, &str, &ip, &bin))
+		require.Equal(t, now.Format(time.RFC3339Nano), tsStr)
+
+		// NULLs
+		require.True(t, rows.Next())
+		require.Error(t, rows.Scan(&ts, &short, &ulong, &flt, &dbl, &str, &ip, &bin))
+		var nullShort sql.NullInt16
+		var nullFlt *float64
+		var nullIP sql.NullString
+		require.NoError(t, rows.Scan(&ts, &nullShort, &ulong, &nullFlt, &dbl, &str, &nullIP, &bin))
+		require.False(t, nullShort.Valid)
+		require.False(t, ulong.Valid)
+		require.Nil(t, nullFlt)
+		require.Nil(t, dbl)
+		require.Nil(t, str)
+		require.False(t, nullIP.Valid)
+		require.Nil(t, bin)
+
+		require.False(t, rows.Next())
+		require.NoError(t, rows.Err())
+		require.Error(t, rows.Scan(&ts, &short, &ulong, &flt, &dbl, &str, &ip, &bin))
+		require.NoError(t, rows.Close())
+	}
+
+	stmt, err := conn.NewStmt()
+	require.NoError(t, err)
+	defer stmt.Close()
+	require.NoError(t, stmt.Prepare(query))
+	require.NoError(t, stmt.Execute())
+	rows, err := stmt.Rows()
+	require.NoError(t, err)
+	check(rows)
+
+	var cliConn unsafe.Pointer
+	err = mach.CliConnect(global.CliEnv, fmt.Sprintf("SERVER=127.0.0.1;UID=SYS;PWD=MANAGER;CONNTYPE=1;PORT_NO=%d", machPort), &cliConn)
+	require.NoError(t, err)
+	defer mach.CliDisconnect(cliConn)
+	var cliStmt unsafe.Pointer
+	require.NoError(t, mach.CliAllocStmt(cliConn, &cliStmt))
+	defer mach.CliFreeStmt(cliStmt)
+	require.NoError(t, mach.CliPrepare(cliStmt, query))
+	require.NoError(t, mach.CliExecute(cliStmt))
+	rows, err = mach.CliRows(cliStmt)
+	require.NoError(t, err)
+	check(rows)
+}
@@ -0,0 +1,200 @@
+package mach
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"unsafe"
+)
+
+// ScriptStatement is a statement of a SQL script.
+type ScriptStatement struct {
+	SQL  string
+	Line int // the line number where the statement starts, 1-based
+}
+
+// ScriptResult is the result of a statement that ExecScript() executed.
+type ScriptResult struct {
+	ScriptStatement
+	StmtType     int // the type of EngStmtType() or CliGetStmtType()
+	Kind         StmtKind
+	RowsAffected int64
+	Err          error
+}
+
+// ScriptError is the error of a statement of ExecScript().
+type ScriptError struct {
+	Line int
+	SQL  string
+	Err  error
+}
+
+func (e *ScriptError) Error() string {
+	return fmt.Sprintf("MachScript line %d: %s", e.Line, e.Err.Error())
+}
+
+func (e *ScriptError) Unwrap() error {
+	return e.Err
+}
+
+// SplitScript splits the SQL script into the statements that end with ';'.
+// ';' in the quoted texts and the comments does not end a statement.
+// The comments are removed except the hints, `/*+ ... */`.
+// An EXEC statement also ends at the end of the line, outside of the parentheses,
+// since the procedure calls are usually written without ';'.
+func SplitScript(script string) []ScriptStatement {
+	var ret []ScriptStatement
+	sb := &strings.Builder{}
+	// head is the beginning of the statement to find EXEC, it is enough with 5 bytes
+	head := make([]byte, 0, 5)
+	line, startLine, depth := 1, 0, 0
+	mark := func() {
+		if startLine == 0 {
+			startLine = line
+		}
+	}
+	write := func(s string) {
+		sb.WriteString(s)
+		if startLine != 0 && len(head) < cap(head) {
+			head = append(head, s[:min(len(s), cap(head)-len(head))]...)
+		}
+	}
+	flush := func() {
+		if sqlText := strings.TrimSpace(sb.String()); sqlText != "" {
+			ret = append(ret, ScriptStatement{SQL: sqlText, Line: startLine})
+		}
+		sb.Reset()
+		head = head[:0]
+		startLine, depth = 0, 0
+	}
+	for i := 0; i < len(script); {
+		c := script[i]
+		switch {
+		case c == '\'' || c == '"':
+			mark()
+			end := sqlQuotedEnd(script, i)
+			write(script[i:end])
+			line += strings.Count(script[i:end], "\n")
+			i = end
+		case c == '-' && strings.HasPrefix(script[i:], "--"):
+			end := strings.IndexByte(script[i:], '\n')
+			if end < 0 {
+				end = len(script) - i
+			}
+			i += end
+		case c == '/' && strings.HasPrefix(script[i:], "/*"):
+			end := strings.Index(script[i+2:], "*/")
+			if end < 0 {
+				end = len(script) - i
+			} else {
+				end += 4
+			}
+			if strings.HasPrefix(script[i:], "/*+") {
+				mark()
+				write(script[i : i+end])
+			} else {
+				write(" ")
+			}
+			line += strings.Count(script[i:i+end], "\n")
+			i += end
+		case c == ';':
+			flush()
+			i++
+		case c == '\n':
+			if depth == 0 && scriptIsExec(string(head)) {
+				flush()
+			} else {
+				write("\n")
+			}
+			line++
+			i++
+		default:
+			switch c {
+			case '(':
+				depth++
+			case ')':
+				if depth > 0 {
+					depth--
+				}
+			}
+			if c != ' ' && c != '\t' && c != '\r' {
+				mark()
+			}
+			write(script[i : i+1])
+			i++
+		}
+	}
+	flush()
+	return ret
+}
+
+// scriptIsExec returns true if sqlText is an EXEC statement.
+func scriptIsExec(sqlText string) bool {
+	s := strings.TrimSpace(sqlText)
+	if len(s) < 4 || !strings.EqualFold(s[:4], "EXEC") {
+		return false
+	}
+	return len(s) == 4 || s[4] == ' ' || s[4] == '\t' || s[4] == '('
+}
+
+// ExecScript executes the statements of the SQL script in order, each by a new statement of the connection.
+// It returns the results of the executed statements, the statements after a failed one are skipped
+// by BatchStopOnError. The error is the joined *ScriptError of the failed statements.
+// The script stops when ctx ends regardless of policy.
+func (conn *Conn) ExecScript(ctx context.Context, script string, policy BatchPolicy) ([]ScriptResult, error) {
+	return execScript(ctx, script, policy, func(sqlText string) (StmtKind, int, int64, error) {
+		stmt, err := conn.NewStmt()
+		if err != nil {
+			return 0, 0, 0, err
+		}
+		defer stmt.Close()
+		if err := stmt.ExecContext(ctx, sqlText); err != nil {
+			return 0, 0, 0, err
+		}
+		typ, err := EngStmtType(stmt.handle)
+		if err != nil {
+			return 0, 0, 0, err
+		}
+		n, err := EngEffectRows(stmt.handle)
+		return EngStmtKind(typ), typ, n, err
+	})
+}
+
+// CliExecScript is ExecScript() of the CLI connection.
+func CliExecScript(ctx context.Context, conn unsafe.Pointer, script string, policy BatchPolicy) ([]ScriptResult, error) {
+	return execScript(ctx, script, policy, func(sqlText string) (StmtKind, int, int64, error) {
+		var stmt unsafe.Pointer
+		if err := CliAllocStmt(conn, &stmt); err != nil {
+			return 0, 0, 0, err
+		}
+		defer CliFreeStmt(stmt)
+		if err := CliExecContext(ctx, stmt, sqlText); err != nil {
+			return 0, 0, 0, err
+		}
+		typ, err := CliGetStmtType(stmt)
+		if err != nil {
+			return 0, 0, 0, err
+		}
+		n, err := CliRowCount(stmt)
+		return CliStmtKind(typ), typ, n, err
+	})
+}
+
+func execScript(ctx context.Context, script string, policy BatchPolicy, exec func(string) (StmtKind, int, int64, error)) ([]ScriptResult, error) {
+	var ret []ScriptResult
+	var errs []error
+	for _, st := range SplitScript(script) {
+		kind, typ, n, err := exec(st.SQL)
+		result := ScriptResult{ScriptStatement: st, StmtType: typ, Kind: kind, RowsAffected: n}
+		if err != nil {
+			result.Err = &ScriptError{Line: st.Line, SQL: st.SQL, Err: err}
+			errs = append(errs, result.Err)
+		}
+		ret = append(ret, result)
+		if err != nil && (policy == BatchStopOnError || ctx.Err() != nil) {
+			break
+		}
+	}
+	return ret, errors.Join(errs...)
+}
@@ -0,0 +1,97 @@
+package mach_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"unsafe"
+
+	mach "github.com/machbase/neo-engine/v8"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitScript(t *testing.T) {
+	script := `-- fixtures
+create table t1 (a int, b varchar(10)); -- trailing
+insert into t1 values(1, 'a;b''c');
+/* block
+comment */ insert into t1 values(2, "x");
+EXEC table_flush(t1)
+exec rollup_force('a
+b')
+select /*+ SCAN_FORWARD(t1) */ * from t1
+  where a = 1;
+  ;
+`
+	expects := []mach.ScriptStatement{
+		{SQL: `create table t1 (a int, b varchar(10))`, Line: 2},
+		{SQL: `insert into t1 values(1, 'a;b''c')`, Line: 3},
+		{SQL: `insert into t1 values(2, "x")`, Line: 5},
+		{SQL: `EXEC table_flush(t1)`, Line: 6},
+		{SQL: "exec rollup_force('a\nb')", Line: 7},
+		{SQL: "select /*+ SCAN_FORWARD(t1) */ * from t1\n  where a = 1", Line: 9},
+	}
+	require.Equal(t, expects, mach.SplitScript(script))
+	require.Empty(t, mach.SplitScript(" -- nothing\n;\n"))
+
+	// a long statement of many lines
+	long := mach.SplitScript("select 1\n" + strings.Repeat("  , 1\n", 100000) + ";\nexec\n")
+	require.Len(t, long, 2)
+	require.Equal(t, 100000, strings.Count(long[0].SQL, "\n"))
+	require.Equal(t, mach.ScriptStatement{SQL: "exec", Line: 100003}, long[1])
+}
+
+const scriptTestSQL = `
+create volatile table %[1]s (id integer primary key, name varchar(20));
+insert into %[1]s values(1, 'a');
+insert into %[1]s values(2, 'b');
+delete from %[1]s where id = 1;
+insert into not_exists_table values(1);
+drop table %[1]s;
+`
+
+func TestExecScript(t *testing.T) {
+	conn, err := global.Env.ConnectTrust("sys")
+	require.NoError(t, err)
+	defer conn.Close()
+
+	ctx := context.Background()
+	script := fmt.Sprintf(scriptTestSQL, "script_test")
+	results, err := conn.ExecScript(ctx, script, mach.BatchStopOnError)
+	require.Error(t, err)
+	var scriptErr *mach.ScriptError
+	require.True(t, errors.As(err, &scriptErr))
+	require.Equal(t, 6, scriptErr.Line)
+	require.Len(t, results, 5)
+	require.Equal(t, mach.StmtKindDDL, results[0].Kind)
+	require.Equal(t, mach.StmtKindInsert, results[1].Kind)
+	require.Equal(t, int64(1), results[1].RowsAffected)
+	require.Equal(t, mach.StmtKindDelete, results[3].Kind)
+	require.Equal(t, int64(1), results[3].RowsAffected)
+	require.NoError(t, results[3].Err)
+	require.Error(t, results[4].Err)
+
+	// the table still exists
+	results, err = conn.ExecScript(ctx, `drop table script_test;`, mach.BatchStopOnError)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+}
+
+func TestCliExecScript(t *testing.T) {
+	var conn unsafe.Pointer
+	err := mach.CliConnect(global.CliEnv, fmt.Sprintf("SERVER=127.0.0.1;UID=SYS;PWD=MANAGER;CONNTYPE=1;PORT_NO=%d", machPort), &conn)
+	require.NoError(t, err)
+	defer mach.CliDisconnect(conn)
+
+	script := fmt.Sprintf(scriptTestSQL, "cli_script_test")
+	results, err := mach.CliExecScript(context.Background(), conn, script, mach.BatchContinueOnError)
+	require.Error(t, err)
+	require.Len(t, results, 6)
+	require.Equal(t, 6, results[4].Line)
+	require.Error(t, results[4].Err)
+	require.Equal(t, mach.StmtKindDelete, results[3].Kind)
+	require.Equal(t, int64(1), results[3].RowsAffected)
+	require.NoError(t, results[5].Err)
+}
@@ -0,0 +1,127 @@
+package mach
+
+import (
+	"context"
+	"time"
+)
+
+// ShutdownCancelGrace is how long GracefulShutdown() waits for the statements
+// to return after they are canceled.
+var ShutdownCancelGrace = 5 * time.Second
+
+// ShutdownReport is what GracefulShutdown() had to force-close.
+type ShutdownReport struct {
+	// EngConnectionCount() when the shutdown began,
+	// it includes the connections that are not made by the Env.
+	ConnectionCount int
+	// sessions that had running statements at the deadline
+	CanceledSessions []uint64
+	// appenders that were still open
+	ClosedAppenders []AppenderReport
+	// statements that were not freed, the idle ones in the statement cache are not counted
+	ClosedStmts int
+	// connections that were not closed
+	ClosedSessions []uint64
+}
+
+type AppenderReport struct {
+	SessionID    uint64
+	Table        string
+	SuccessCount int64
+	FailCount    int64
+	Err          error
+}
+
+// GracefulShutdown stops accepting new connections and waits until ctx ends for
+// the running statements. The statements still running at the deadline are canceled by EngCancel().
+// Then it closes the open appenders, statements and connections,
+// shuts down and finalizes the env even if it is shared by SharedEnv().
+//
+// If the canceled statements do not return in ShutdownCancelGrace,
+// it returns error and the env is left started, accepting new connections again.
+func (env *Env) GracefulShutdown(ctx context.Context) (_ *ShutdownReport, err error) {
+	env.mu.Lock()
+	if err := env.check("GracefulShutdown", EnvStateStarted); err != nil {
+		env.mu.Unlock()
+		return nil, err
+	}
+	env.draining = true
+	report := &ShutdownReport{ConnectionCount: EngConnectionCount(env.handle)}
+	env.mu.Unlock()
+	defer func() {
+		if err != nil {
+			env.mu.Lock()
+			env.draining = false
+			env.mu.Unlock()
+		}
+	}()
+
+	if !env.waitIdle(ctx) {
+		for _, conn := range env.Conns() {
+			if conn.active() == 0 {
+				continue
+			}
+			if err := conn.Cancel(); err == nil {
+				report.CanceledSessions = append(report.CanceledSessions, conn.sessionID)
+			}
+		}
+		graceCtx, cancel := context.WithTimeout(context.Background(), ShutdownCancelGrace)
+		idle := env.waitIdle(graceCtx)
+		cancel()
+		if !idle {
+			return report, ErrEnvShutdownBusy(len(report.CanceledSessions))
+		}
+	}
+
+	for _, pool := range env.Pools() {
+		pool.Close()
+	}
+	for _, conn := range env.Conns() {
+		cached := map[*Stmt]struct{}{}
+		for _, stmt := range conn.stmtCache.drain() {
+			cached[stmt] = struct{}{}
+		}
+		conn.mu.Lock()
+		for stmt := range conn.stmts {
+			if stmt.appendTable != "" {
+				ar := AppenderReport{SessionID: conn.sessionID, Table: stmt.appendTable}
+				ar.SuccessCount, ar.FailCount, ar.Err = EngAppendClose(stmt.handle)
+				report.ClosedAppenders = append(report.ClosedAppenders, ar)
+				stmt.appendTable = ""
+			}
+			if _, ok := cached[stmt]; !ok {
+				report.ClosedStmts++
+			}
+		}
+		conn.mu.Unlock()
+		if err := conn.Close(); err != nil && !conn.isClosed() {
+			return report, err
+		}
+		report.ClosedSessions = append(report.ClosedSessions, conn.sessionID)
+	}
+
+	if err := env.close(true); err != nil {
+		return report, err
+	}
+	return report, nil
+}
+
+// waitIdle returns true when no statements are running, false if ctx ends first.
+func (env *Env) waitIdle(ctx context.Context) bool {
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		active := 0
+		for _, conn := range env.Conns() {
+			active += conn.active()
+		}
+		if active == 0 {
+			return true
+		}
+		select {
+		case <-ctx.Done():
+			return false
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,222 @@
+package mach
+
+import (
+	"container/list"
+	"sync"
+	"unsafe"
+)
+
+// DefaultStmtCacheSize is the number of the prepared statements that a connection keeps.
+const DefaultStmtCacheSize = 16
+
+// StmtCacheStats is the statistics of the prepared statement cache of a connection.
+type StmtCacheStats struct {
+	Capacity  int    `json:"capacity"`
+	Size      int    `json:"size"`      // statements that are idle in the cache
+	Hits      uint64 `json:"hits"`      // statements that were reused
+	Misses    uint64 `json:"misses"`    // statements that were prepared
+	Evictions uint64 `json:"evictions"` // statements that were freed by the capacity
+}
+
+// stmtLRU is the LRU list of the prepared statements keyed by the SQL text.
+// A statement is taken out of the list while it is used, and put back when it is released.
+type stmtLRU[T any] struct {
+	mu       sync.Mutex
+	capacity int
+	ll       *list.List // front is the most recently used
+	items    map[string]*list.Element
+	closed   bool
+	stats    StmtCacheStats
+}
+
+type stmtLRUEntry[T any] struct {
+	key  string
+	stmt T
+}
+
+func newStmtLRU[T any](capacity int) *stmtLRU[T] {
+	return &stmtLRU[T]{capacity: capacity, ll: list.New(), items: map[string]*list.Element{}}
+}
+
+// take removes the statement of key from the list.
+func (c *stmtLRU[T]) take(key string) (T, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elm, ok := c.items[key]; ok {
+		c.ll.Remove(elm)
+		delete(c.items, key)
+		c.stats.Hits++
+		return elm.Value.(*stmtLRUEntry[T]).stmt, true
+	}
+	c.stats.Misses++
+	var zero T
+	return zero, false
+}
+
+// put adds the statement of key to the list, and returns the statements that should be freed.
+// The statement itself is returned if the list has the one of the same key, or it is closed.
+func (c *stmtLRU[T]) put(key string, stmt T) []T {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, exists := c.items[key]; exists || c.closed || c.capacity <= 0 {
+		return []T{stmt}
+	}
+	c.items[key] = c.ll.PushFront(&stmtLRUEntry[T]{key: key, stmt: stmt})
+	return c.evict()
+}
+
+// resize changes the capacity, and returns the statements that should be freed.
+func (c *stmtLRU[T]) resize(capacity int) []T {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.capacity = capacity
+	return c.evict()
+}
+
+// evict removes the least recently used statements over the capacity, the caller should hold mu.
+func (c *stmtLRU[T]) evict() []T {
+	var ret []T
+	for c.ll.Len() > max(c.capacity, 0) {
+		entry := c.ll.Remove(c.ll.Back()).(*stmtLRUEntry[T])
+		delete(c.items, entry.key)
+		c.stats.Evictions++
+		ret = append(ret, entry.stmt)
+	}
+	return ret
+}
+
+// drain closes the list, and returns all the statements in it.
+func (c *stmtLRU[T]) drain() []T {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closed = true
+	ret := make([]T, 0, c.ll.Len())
+	for elm := c.ll.Front(); elm != nil; elm = elm.Next() {
+		ret = append(ret, elm.Value.(*stmtLRUEntry[T]).stmt)
+	}
+	c.ll.Init()
+	c.items = map[string]*list.Element{}
+	return ret
+}
+
+func (c *stmtLRU[T]) Stats() StmtCacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ret := c.stats
+	ret.Capacity = c.capacity
+	ret.Size = c.ll.Len()
+	return ret
+}
+
+// Prepared returns the statement that is prepared with sqlText.
+// It reuses the statement of the same SQL text in the cache of the connection,
+// or allocates and prepares a new one. The statement should be returned by Release().
+func (conn *Conn) Prepared(sqlText string) (*Stmt, error) {
+	if stmt, ok := conn.stmtCache.take(sqlText); ok {
+		return stmt, nil
+	}
+	stmt, err := conn.NewStmt()
+	if err != nil {
+		return nil, err
+	}
+	if err := stmt.Prepare(sqlText); err != nil {
+		stmt.Close()
+		return nil, err
+	}
+	stmt.cacheKey = sqlText
+	return stmt, nil
+}
+
+// SetStmtCacheSize changes the number of the prepared statements that the connection keeps,
+// 0 disables the cache. The statements over the size are freed.
+func (conn *Conn) SetStmtCacheSize(size int) {
+	for _, stmt := range conn.stmtCache.resize(size) {
+		stmt.Close()
+	}
+}
+
+func (conn *Conn) StmtCacheStats() StmtCacheStats {
+	return conn.stmtCache.Stats()
+}
+
+// Release resets the statement from Conn.Prepared() by ExecuteClean() and returns it
+// into the cache of the connection. The least recently used statement is freed
+// if the cache is full. The other statements are closed.
+func (stmt *Stmt) Release() error {
+	if stmt.cacheKey == "" {
+		return stmt.Close()
+	}
+	if err := stmt.ExecuteClean(); err != nil {
+		stmt.Close()
+		return err
+	}
+	for _, evicted := range stmt.conn.stmtCache.put(stmt.cacheKey, stmt) {
+		evicted.Close()
+	}
+	return nil
+}
+
+// CliStmtCache is the cache of the prepared statements of a CLI connection.
+// It should be closed before the connection is disconnected.
+type CliStmtCache struct {
+	conn unsafe.Pointer
+	lru  *stmtLRU[unsafe.Pointer]
+}
+
+// NewCliStmtCache returns the cache that keeps size prepared statements of the connection.
+func NewCliStmtCache(conn unsafe.Pointer, size int) *CliStmtCache {
+	return &CliStmtCache{conn: conn, lru: newStmtLRU[unsafe.Pointer](size)}
+}
+
+// Prepared returns the statement handle that is prepared with sqlText.
+// The handle should be returned by Release() with the same sqlText, or freed by CliFreeStmt().
+func (c *CliStmtCache) Prepared(sqlText string) (unsafe.Pointer, error) {
+	if stmt, ok := c.lru.take(sqlText); ok {
+		return stmt, nil
+	}
+	var stmt unsafe.Pointer
+	if err := CliAllocStmt(c.conn, &stmt); err != nil {
+		return nil, err
+	}
+	if err := CliPrepare(stmt, sqlText); err != nil {
+		CliFreeStmt(stmt)
+		return nil, err
+	}
+	return stmt, nil
+}
+
+// Release resets the statement by CliExecuteClean() and returns it into the cache.
+// The least recently used statement is freed if the cache is full.
+func (c *CliStmtCache) Release(sqlText string, stmt unsafe.Pointer) error {
+	if err := CliExecuteClean(stmt); err != nil {
+		CliFreeStmt(stmt)
+		return err
+	}
+	for _, evicted := range c.lru.put(sqlText, stmt) {
+		CliFreeStmt(evicted)
+	}
+	return nil
+}
+
+// SetSize changes the number of the prepared statements that the cache keeps,
+// 0 disables the cache. The statements over the size are freed.
+func (c *CliStmtCache) SetSize(size int) {
+	for _, stmt := range c.lru.resize(size) {
+		CliFreeStmt(stmt)
+	}
+}
+
+func (c *CliStmtCache) Stats() StmtCacheStats {
+	return c.lru.Stats()
+}
+
+// Close frees all the statements in the cache, the statements released later are freed.
+func (c *CliStmtCache) Close() error {
+	var err error
+	for _, stmt := range c.lru.drain() {
+		if e := CliFreeStmt(stmt); e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
@@ -0,0 +1,87 @@
+package mach_test
+
+import (
+	"fmt"
+	"testing"
+	"unsafe"
+
+	mach "github.com/machbase/neo-engine/v8"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStmtCache(t *testing.T) {
+	conn, err := global.Env.ConnectTrust("sys")
+	require.NoError(t, err)
+	defer conn.Close()
+	require.Equal(t, mach.DefaultStmtCacheSize, conn.StmtCacheStats().Capacity)
+
+	query := `select count(*) from m$sys_users where name = ?`
+	for i := 0; i < 3; i++ {
+		stmt, err := conn.Prepared(query)
+		require.NoError(t, err)
+		require.NoError(t, mach.Bind(stmt.Handle(), 0, "SYS"))
+		require.NoError(t, stmt.Execute())
+		exists, err := stmt.Fetch()
+		require.NoError(t, err)
+		require.True(t, exists)
+		require.NoError(t, stmt.Release())
+	}
+	stats := conn.StmtCacheStats()
+	require.Equal(t, uint64(2), stats.Hits)
+	require.Equal(t, uint64(1), stats.Misses)
+	require.Equal(t, 1, stats.Size)
+
+	// the statements in use are not shared
+	stmt1, err := conn.Prepared(query)
+	require.NoError(t, err)
+	stmt2, err := conn.Prepared(query)
+	require.NoError(t, err)
+	require.NotSame(t, stmt1, stmt2)
+	require.NoError(t, stmt1.Release())
+	require.NoError(t, stmt2.Release())
+	require.Equal(t, 1, conn.StmtCacheStats().Size)
+
+	// eviction
+	conn.SetStmtCacheSize(1)
+	other, err := conn.Prepared(`select count(*) from m$sys_users`)
+	require.NoError(t, err)
+	require.NoError(t, other.Release())
+	stats = conn.StmtCacheStats()
+	require.Equal(t, 1, stats.Size)
+	require.Equal(t, uint64(1), stats.Evictions)
+
+	_, err = conn.Prepared(`select * from not_exists_table`)
+	require.Error(t, err)
+
+	require.NoError(t, conn.Close())
+	_, err = conn.Prepared(query)
+	require.Error(t, err)
+}
+
+func TestCliStmtCache(t *testing.T) {
+	var conn unsafe.Pointer
+	err := mach.CliConnect(global.CliEnv, fmt.Sprintf("SERVER=127.0.0.1;UID=SYS;PWD=MANAGER;CONNTYPE=1;PORT_NO=%d", machPort), &conn)
+	require.NoError(t, err)
+	defer mach.CliDisconnect(conn)
+
+	cache := mach.NewCliStmtCache(conn, 1)
+	queries := []string{`select count(*) from m$sys_users`, `select count(*) from m$sys_tables`}
+	for i := 0; i < 4; i++ {
+		query := queries[i/2]
+		stmt, err := cache.Prepared(query)
+		require.NoError(t, err)
+		require.NoError(t, mach.CliExecute(stmt))
+		end, err := mach.CliFetch(stmt)
+		require.NoError(t, err)
+		require.False(t, end)
+		require.NoError(t, cache.Release(query, stmt))
+	}
+	stats := cache.Stats()
+	require.Equal(t, uint64(2), stats.Hits)
+	require.Equal(t, uint64(2), stats.Misses)
+	require.Equal(t, uint64(1), stats.Evictions)
+	require.Equal(t, 1, stats.Size)
+
+	require.NoError(t, cache.Close())
+	require.Equal(t, 0, cache.Stats().Size)
+}
@@ -0,0 +1,240 @@
+package mach
+
+import (
+	"context"
+	"unsafe"
+)
+
+// StmtKind is the kind of the statement, classified from the statement type of
+// EngStmtType() by EngStmtKind() or CliGetStmtType() by CliStmtKind().
+// The engine and the CLI number DELETE, INSERT_SELECT and UPDATE differently.
+type StmtKind int
+
+const (
+	StmtKindUnknown StmtKind = iota
+	StmtKindDDL
+	StmtKindAlterSystem
+	StmtKindSelect
+	StmtKindInsert
+	StmtKindDelete
+	StmtKindInsertSelect
+	StmtKindUpdate
+	StmtKindExecRollup
+)
+
+var stmtKindNames = [...]string{
+	StmtKindUnknown:      "UNKNOWN",
+	StmtKindDDL:          "DDL",
+	StmtKindAlterSystem:  "ALTER SYSTEM",
+	StmtKindSelect:       "SELECT",
+	StmtKindInsert:       "INSERT",
+	StmtKindDelete:       "DELETE",
+	StmtKindInsertSelect: "INSERT_SELECT",
+	StmtKindUpdate:       "UPDATE",
+	StmtKindExecRollup:   "EXEC_ROLLUP",
+}
+
+func (kind StmtKind) String() string {
+	if kind < 0 || int(kind) >= len(stmtKindNames) {
+		return stmtKindNames[StmtKindUnknown]
+	}
+	return stmtKindNames[kind]
+}
+
+// HasRows returns true if the statement of the kind returns a result set.
+func (kind StmtKind) HasRows() bool {
+	return kind == StmtKindSelect
+}
+
+// EngStmtKind classifies the statement type of EngStmtType().
+//
+//	DDL: 1-255
+//	ALTER SYSTEM: 256-511
+//	SELECT: 512
+//	INSERT: 513
+//	DELETE: 514-517
+//	INSERT_SELECT: 518
+//	UPDATE: 519
+//	EXEC_ROLLUP: 1000-1002
+func EngStmtKind(typ int) StmtKind {
+	switch {
+	case typ >= 514 && typ <= 517:
+		return StmtKindDelete
+	case typ == 518:
+		return StmtKindInsertSelect
+	case typ == 519:
+		return StmtKindUpdate
+	}
+	return stmtKindCommon(typ)
+}
+
+// CliStmtKind classifies the statement type of CliGetStmtType().
+//
+//	unknown: -1
+//	DDL: 1-255
+//	ALTER SYSTEM: 256-511
+//	SELECT: 512
+//	INSERT: 513
+//	DELETE: 514-518
+//	INSERT_SELECT: 519
+//	UPDATE: 520
+//	EXEC_ROLLUP: 1000-1002
+func CliStmtKind(typ int) StmtKind {
+	switch {
+	case typ >= 514 && typ <= 518:
+		return StmtKindDelete
+	case typ == 519:
+		return StmtKindInsertSelect
+	case typ == 520:
+		return StmtKindUpdate
+	}
+	return stmtKindCommon(typ)
+}
+
+// stmtKindCommon classifies the statement types that the engine and the CLI number the same.
+func stmtKindCommon(typ int) StmtKind {
+	switch {
+	case typ >= 1 && typ <= 255:
+		return StmtKindDDL
+	case typ >= 256 && typ <= 511:
+		return StmtKindAlterSystem
+	case typ == 512:
+		return StmtKindSelect
+	case typ == 513:
+		return StmtKindInsert
+	case typ >= 1000 && typ <= 1002:
+		return StmtKindExecRollup
+	default:
+		return StmtKindUnknown
+	}
+}
+
+// ExecuteResult is the result of Execute() and CliExecuteConn().
+// Columns and Rows are set if the statement returns rows, otherwise RowsAffected is set.
+type ExecuteResult struct {
+	Kind         StmtKind
+	RowsAffected int64
+	Columns      []Column
+	Rows         [][]any // NULL is nil
+}
+
+// Execute prepares sqlText with args bound by Bind(), executes it and
+// fetches all the rows or reports the affected rows by the kind of the statement.
+// The statement is canceled when ctx ends.
+func Execute(ctx context.Context, conn *Conn, sqlText string, args ...any) (*ExecuteResult, error) {
+	stmt, err := conn.NewStmt()
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+	err = runContext(ctx, conn.Cancel, func() error {
+		if err := stmt.Prepare(sqlText); err != nil {
+			return err
+		}
+		for idx, arg := range args {
+			if err := Bind(stmt.handle, idx, arg); err != nil {
+				return err
+			}
+		}
+		return stmt.Execute()
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.ExecuteClean()
+	typ, err := EngStmtType(stmt.handle)
+	if err != nil {
+		return nil, err
+	}
+	ret := &ExecuteResult{Kind: EngStmtKind(typ)}
+	if !ret.Kind.HasRows() {
+		if ret.RowsAffected, err = EngEffectRows(stmt.handle); err != nil {
+			return nil, err
+		}
+		return ret, nil
+	}
+	if ret.Columns, err = EngColumns(stmt.handle); err != nil {
+		return nil, err
+	}
+	for {
+		next, err := stmt.FetchContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if !next {
+			break
+		}
+		row := make([]any, len(ret.Columns))
+		for i := range ret.Columns {
+			v, valid, err := engColumnValue(stmt.handle, i)
+			if err != nil {
+				return nil, err
+			}
+			if valid {
+				row[i] = v
+			}
+		}
+		ret.Rows = append(ret.Rows, row)
+	}
+	return ret, nil
+}
+
+// CliExecuteConn is Execute() on a new statement of the CLI connection, args are bound by CliBind().
+func CliExecuteConn(ctx context.Context, conn unsafe.Pointer, sqlText string, args ...any) (*ExecuteResult, error) {
+	var stmt unsafe.Pointer
+	if err := CliAllocStmt(conn, &stmt); err != nil {
+		return nil, err
+	}
+	defer CliFreeStmt(stmt)
+	err := runContext(ctx, cliCancelFunc(stmt), func() error {
+		if err := CliPrepare(stmt, sqlText); err != nil {
+			return err
+		}
+		for idx, arg := range args {
+			if err := CliBind(stmt, idx, arg); err != nil {
+				return err
+			}
+		}
+		return CliExecute(stmt)
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer CliExecuteClean(stmt)
+	typ, err := CliGetStmtType(stmt)
+	if err != nil {
+		return nil, err
+	}
+	ret := &ExecuteResult{Kind: CliStmtKind(typ)}
+	if !ret.Kind.HasRows() {
+		if ret.RowsAffected, err = CliRowCount(stmt); err != nil {
+			return nil, err
+		}
+		return ret, nil
+	}
+	if ret.Columns, err = CliColumns(stmt); err != nil {
+		return nil, err
+	}
+	bufs := make([][]byte, len(ret.Columns))
+	for {
+		end, err := CliFetchContext(ctx, stmt)
+		if err != nil {
+			return nil, err
+		}
+		if end {
+			break
+		}
+		row := make([]any, len(ret.Columns))
+		for i, col := range ret.Columns {
+			v, valid, err := cliColumnValue(stmt, i, col, &bufs[i])
+			if err != nil {
+				return nil, err
+			}
+			if valid {
+				row[i] = v
+			}
+		}
+		ret.Rows = append(ret.Rows, row)
+	}
+	return ret, nil
+}
@@ -0,0 +1,110 @@
+package mach_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"unsafe"
+
+	mach "github.com/machbase/neo-engine/v8"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStmtKind(t *testing.T) {
+	tests := []struct {
+		typ int
+		eng mach.StmtKind
+		cli mach.StmtKind
+	}{
+		{-1, mach.StmtKindUnknown, mach.StmtKindUnknown},
+		{0, mach.StmtKindUnknown, mach.StmtKindUnknown},
+		{1, mach.StmtKindDDL, mach.StmtKindDDL},
+		{255, mach.StmtKindDDL, mach.StmtKindDDL},
+		{256, mach.StmtKindAlterSystem, mach.StmtKindAlterSystem},
+		{511, mach.StmtKindAlterSystem, mach.StmtKindAlterSystem},
+		{512, mach.StmtKindSelect, mach.StmtKindSelect},
+		{513, mach.StmtKindInsert, mach.StmtKindInsert},
+		{514, mach.StmtKindDelete, mach.StmtKindDelete},
+		{517, mach.StmtKindDelete, mach.StmtKindDelete},
+		{518, mach.StmtKindInsertSelect, mach.StmtKindDelete},
+		{519, mach.StmtKindUpdate, mach.StmtKindInsertSelect},
+		{520, mach.StmtKindUnknown, mach.StmtKindUpdate},
+		{521, mach.StmtKindUnknown, mach.StmtKindUnknown},
+		{522, mach.StmtKindUnknown, mach.StmtKindUnknown},
+		{524, mach.StmtKindUnknown, mach.StmtKindUnknown},
+		{999, mach.StmtKindUnknown, mach.StmtKindUnknown},
+		{1000, mach.StmtKindExecRollup, mach.StmtKindExecRollup},
+		{1002, mach.StmtKindExecRollup, mach.StmtKindExecRollup},
+		{1003, mach.StmtKindUnknown, mach.StmtKindUnknown},
+	}
+	for _, tt := range tests {
+		require.Equal(t, tt.eng, mach.EngStmtKind(tt.typ), "engine %d", tt.typ)
+		require.Equal(t, tt.cli, mach.CliStmtKind(tt.typ), "cli %d", tt.typ)
+		require.Equal(t, tt.cli, mach.StmtType(tt.typ).Kind(), "type %d", tt.typ)
+	}
+	require.True(t, mach.StmtType(1001).IsExecRollup())
+	require.False(t, mach.StmtType(523).IsExecRollup())
+	require.True(t, mach.StmtType(520).IsUpdate())
+
+	names := map[mach.StmtKind]string{
+		mach.StmtKindUnknown:      "UNKNOWN",
+		mach.StmtKindDDL:          "DDL",
+		mach.StmtKindAlterSystem:  "ALTER SYSTEM",
+		mach.StmtKindSelect:       "SELECT",
+		mach.StmtKindInsert:       "INSERT",
+		mach.StmtKindDelete:       "DELETE",
+		mach.StmtKindInsertSelect: "INSERT_SELECT",
+		mach.StmtKindUpdate:       "UPDATE",
+		mach.StmtKindExecRollup:   "EXEC_ROLLUP",
+		mach.StmtKind(-1):         "UNKNOWN",
+		mach.StmtKind(100):        "UNKNOWN",
+	}
+	for kind, name := range names {
+		require.Equal(t, name, kind.String())
+	}
+	require.True(t, mach.StmtKindSelect.HasRows())
+	require.False(t, mach.StmtKindInsert.HasRows())
+}
+
+func TestExecute(t *testing.T) {
+	conn, err := global.Env.ConnectTrust("sys")
+	require.NoError(t, err)
+	defer conn.Close()
+	ctx := context.Background()
+
+	result, err := mach.Execute(ctx, conn, `create volatile table execute_test (id integer primary key, name varchar(20))`)
+	require.NoError(t, err)
+	require.Equal(t, mach.StmtKindDDL, result.Kind)
+	defer mach.Execute(ctx, conn, `drop table execute_test`)
+
+	result, err = mach.Execute(ctx, conn, `insert into execute_test values(?, ?)`, 1, "a")
+	require.NoError(t, err)
+	require.Equal(t, mach.StmtKindInsert, result.Kind)
+	require.Equal(t, int64(1), result.RowsAffected)
+	_, err = mach.Execute(ctx, conn, `insert into execute_test values(?, ?)`, 2, nil)
+	require.NoError(t, err)
+
+	result, err = mach.Execute(ctx, conn, `select id, name from execute_test order by id`)
+	require.NoError(t, err)
+	require.Equal(t, mach.StmtKindSelect, result.Kind)
+	require.Len(t, result.Columns, 2)
+	require.Equal(t, [][]any{{int32(1), "a"}, {int32(2), nil}}, result.Rows)
+
+	var cliConn unsafe.Pointer
+	err = mach.CliConnect(global.CliEnv, fmt.Sprintf("SERVER=127.0.0.1;UID=SYS;PWD=MANAGER;CONNTYPE=1;PORT_NO=%d", machPort), &cliConn)
+	require.NoError(t, err)
+	defer mach.CliDisconnect(cliConn)
+
+	result, err = mach.CliExecuteConn(ctx, cliConn, `select id, name from execute_test where id = ?`, 1)
+	require.NoError(t, err)
+	require.Equal(t, mach.StmtKindSelect, result.Kind)
+	require.Equal(t, [][]any{{int32(1), "a"}}, result.Rows)
+
+	result, err = mach.CliExecuteConn(ctx, cliConn, `delete from execute_test where id = ?`, 2)
+	require.NoError(t, err)
+	require.Equal(t, mach.StmtKindDelete, result.Kind)
+	require.Equal(t, int64(1), result.RowsAffected)
+
+	_, err = mach.Execute(ctx, conn, `select * from not_exists_table`)
+	require.Error(t, err)
+}
@@ -1,11 +1,14 @@
 package mach_test
 
 import (
+	"bytes"
+	"context"
 	_ "embed"
 	"fmt"
 	"net"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 	"unsafe"
@@ -20,6 +23,7 @@ var machPort = 15656
 var machbase_conf []byte
 
 var global = struct {
+	Env    *mach.Env
 	SvrEnv unsafe.Pointer
 	CliEnv unsafe.Pointer
 }{}
@@ -29,29 +33,38 @@ func TestMain(m *testing.M) {
 	if err != nil {
 		panic(err)
 	}
-	confPath := filepath.Join(homePath, "conf", "machbase.conf")
+	conf, err := mach.ParseConfig(bytes.NewReader(machbase_conf))
+	if err != nil {
+		panic(err)
+	}
 
 	os.RemoveAll(homePath)
-	os.MkdirAll(homePath, 0755)
-	os.MkdirAll(filepath.Join(homePath, "conf"), 0755)
-	os.MkdirAll(filepath.Join(homePath, "trc"), 0755)
-	os.MkdirAll(filepath.Join(homePath, "dbs"), 0755)
-	os.WriteFile(confPath, machbase_conf, 0644)
-
-	var svrEnvHandle unsafe.Pointer
-	if err := mach.EngInitialize(homePath, machPort, 0x0, &svrEnvHandle); err != nil {
+	if _, err := mach.SetupHome(homePath, conf); err != nil {
+		panic(err)
+	}
+
+	env, err := mach.NewEnv(homePath, machPort)
+	if err != nil {
 		panic(err)
 	}
-	global.SvrEnv = svrEnvHandle
+	global.Env = env
+	global.SvrEnv = env.Handle()
 
-	if !mach.EngExistsDatabase(global.SvrEnv) {
-		mach.EngCreateDatabase(global.SvrEnv)
+	if !env.ExistsDatabase() {
+		if err := env.CreateDatabase(); err != nil {
+			panic(err)
+		}
 	}
 
-	if err := mach.EngStartup(global.SvrEnv); err != nil {
+	if err := env.Startup(); err != nil {
+		panic(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	err = env.WaitReady(ctx)
+	cancel()
+	if err != nil {
 		panic(err)
 	}
-	time.Sleep(time.Millisecond * 4000)
 
 	var cliEnvHandler unsafe.Pointer
 	if err := mach.CliInitialize(&cliEnvHandler); err != nil {
@@ -64,15 +77,17 @@ func TestMain(m *testing.M) {
 	if err := mach.CliFinalize(global.CliEnv); err != nil {
 		panic(err)
 	}
-	if err := mach.EngShutdown(global.SvrEnv); err != nil {
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Second)
+	_, err = global.Env.GracefulShutdown(ctx)
+	cancel()
+	if err != nil {
 		panic(err)
 	}
-	mach.EngFinalize(global.SvrEnv)
 	os.RemoveAll(homePath)
 }
 
 func TestAll(t *testing.T) {
-	createTables()
+	createTables(t)
 	tests := []struct {
 		name string
 		tc   func(t *testing.T)
@@ -89,31 +104,14 @@ func TestAll(t *testing.T) {
 			tc.tc(t)
 		})
 	}
-	dropTables()
+	dropTables(t)
 }
 
-func createTables() {
-	var conn unsafe.Pointer
-	var stmt unsafe.Pointer
-
-	// trace_log_level
-	mach.EngConnectTrust(global.SvrEnv, "sys", &conn)
-	mach.EngAllocStmt(conn, &stmt)
-	mach.EngDirectExecute(stmt, "alter system set trace_log_level=1024")
-	mach.EngFreeStmt(stmt)
-	mach.EngDisconnect(conn)
-
-	// create tag table simple_tag
-	mach.EngConnectTrust(global.SvrEnv, "sys", &conn)
-	mach.EngAllocStmt(conn, &stmt)
-	mach.EngDirectExecute(stmt, `create tag table if not exists simple_tag (name varchar(100) primary key, time datetime basetime, value double)`)
-	mach.EngFreeStmt(stmt)
-	mach.EngDisconnect(conn)
-
-	// create tag table tag_data
-	mach.EngConnectTrust(global.SvrEnv, "sys", &conn)
-	mach.EngAllocStmt(conn, &stmt)
-	mach.EngDirectExecute(stmt, `
+func createTables(tb testing.TB) {
+	execScript(tb, `
+		-- trace_log_level
+		alter system set trace_log_level=1024;
+		create tag table if not exists simple_tag (name varchar(100) primary key, time datetime basetime, value double);
 		create tag table tag_data(
 			name            varchar(100) primary key, 
 			time            datetime basetime, 
@@ -128,15 +126,7 @@ func createTables() {
 			json_value      json,
 			ipv4_value      ipv4,
 			ipv6_value      ipv6
-		)
-	`)
-	mach.EngFreeStmt(stmt)
-	mach.EngDisconnect(conn)
-
-	// create log table log_data
-	mach.EngConnectTrust(global.SvrEnv, "sys", &conn)
-	mach.EngAllocStmt(conn, &stmt)
-	mach.EngDirectExecute(stmt, `
+		);
 		create table log_data(
 		    time datetime,
 			short_value short,
@@ -152,36 +142,45 @@ func createTables() {
 			ipv4_value ipv4,
 			ipv6_value ipv6,
 			text_value text,
-			bin_value binary)
+			bin_value binary);
 	`)
-	mach.EngFreeStmt(stmt)
-	mach.EngDisconnect(conn)
 }
 
-func dropTables() {
-	var conn unsafe.Pointer
-	var stmt unsafe.Pointer
-
-	// drop table simple_tag
-	mach.EngConnectTrust(global.SvrEnv, "sys", &conn)
-	mach.EngAllocStmt(conn, &stmt)
-	mach.EngDirectExecute(stmt, `drop table simple_tag`)
-	mach.EngFreeStmt(stmt)
-	mach.EngDisconnect(conn)
-
-	// drop table tag_data
-	mach.EngConnectTrust(global.SvrEnv, "sys", &conn)
-	mach.EngAllocStmt(conn, &stmt)
-	mach.EngDirectExecute(stmt, `drop table tag_data`)
-	mach.EngFreeStmt(stmt)
-	mach.EngDisconnect(conn)
+// dropTables drops the tables of createTables() that exist,
+// a table may not exist if the test failed before creating it.
+func dropTables(tb testing.TB) {
+	tb.Helper()
+	conn, err := global.Env.ConnectTrust("sys")
+	if err != nil {
+		tb.Fatal(err)
+	}
+	defer conn.Close()
+	tables, err := mach.Execute(context.Background(), conn, `select name from m$sys_tables`)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	script := ""
+	for _, table := range []string{"simple_tag", "tag_data", "log_data"} {
+		for _, row := range tables.Rows {
+			if name, ok := row[0].(string); ok && strings.EqualFold(name, table) {
+				script += fmt.Sprintf("drop table %s;\n", table)
+			}
+		}
+	}
+	execScript(tb, script)
+}
 
-	// drop table log_data
-	mach.EngConnectTrust(global.SvrEnv, "sys", &conn)
-	mach.EngAllocStmt(conn, &stmt)
-	mach.EngDirectExecute(stmt, `drop table log_data`)
-	mach.EngFreeStmt(stmt)
-	mach.EngDisconnect(conn)
+// execScript runs the script, it fails tb with the errors of all the failed statements.
+func execScript(tb testing.TB, script string) {
+	tb.Helper()
+	conn, err := global.Env.ConnectTrust("sys")
+	if err != nil {
+		tb.Fatal(err)
+	}
+	defer conn.Close()
+	if _, err := conn.ExecScript(context.Background(), script, mach.BatchContinueOnError); err != nil {
+		tb.Fatal(err)
+	}
 }
 
 func BenchmarkAll(b *testing.B) {
@@ -192,16 +191,17 @@ func BenchmarkAll(b *testing.B) {
 		{name: "benchSimpleTagInsertDirectExecute", bench: benchSimpleTagInsertDirectExecute},
 		{name: "benchSimpleTagInsertExecute", bench: benchSimpleTagInsertExecute},
 		{name: "benchSimpleTagInsertExecute", bench: benchSimpleTagInsertExecute},
+		{name: "benchSimpleTagInsertPrepared", bench: benchSimpleTagInsertPrepared},
 		{name: "benchSimpleTagAppend", bench: benchSimpleTagAppend},
 	}
 
-	createTables()
+	createTables(b)
 	for _, bench := range benches {
 		b.Run(bench.name, func(b *testing.B) {
 			bench.bench(b)
 		})
 	}
-	dropTables()
+	dropTables(b)
 }
 
 func benchSimpleTagInsertDirectExecute(b *testing.B) {
@@ -251,6 +251,29 @@ func benchSimpleTagInsertExecute(b *testing.B) {
 	}
 }
 
+func benchSimpleTagInsertPrepared(b *testing.B) {
+	conn, err := global.Env.ConnectTrust("sys")
+	require.NoError(b, err)
+	defer conn.Close()
+
+	sqlText := `insert into simple_tag values(?, ?, ?)`
+
+	for i := 0; i < b.N; i++ {
+		stmt, err := conn.Prepared(sqlText)
+		require.NoError(b, err)
+		err = mach.EngBindString(stmt.Handle(), 0, "bench-insert")
+		require.NoError(b, err)
+		err = mach.EngBindInt64(stmt.Handle(), 1, time.Now().UnixNano())
+		require.NoError(b, err)
+		err = mach.EngBindFloat64(stmt.Handle(), 2, 1.001*float64(i+1))
+		require.NoError(b, err)
+		err = stmt.Execute()
+		require.NoError(b, err)
+
+		require.NoError(b, stmt.Release())
+	}
+}
+
 func benchSimpleTagAppend(b *testing.B) {
 	var conn unsafe.Pointer
 	var stmt unsafe.Pointer
@@ -684,36 +707,22 @@ func CliTagTableInsertAndSelect(t *testing.T) {
 	}
 
 	// bind
-	shortValue := int16(1) // short_value
-	err = mach.CliBindParam(stmt, 0, mach.MACHCLI_C_TYPE_INT16, mach.MACHCLI_SQL_TYPE_INT16, unsafe.Pointer(&shortValue), 2)
-	require.NoError(t, err, "bind fail")
-	ushortValue := uint16(2) // ushort_value
-	err = mach.CliBindParam(stmt, 1, mach.MACHCLI_C_TYPE_INT16, mach.MACHCLI_SQL_TYPE_INT16, unsafe.Pointer(&ushortValue), 2)
-	require.NoError(t, err, "bind fail")
-	intValue := int(3) // int_value
-	err = mach.CliBindParam(stmt, 2, mach.MACHCLI_C_TYPE_INT32, mach.MACHCLI_SQL_TYPE_INT32, unsafe.Pointer(&intValue), 4)
-	require.NoError(t, err, "bind fail")
-	uintValue := uint(4) // uint_value
-	err = mach.CliBindParam(stmt, 3, mach.MACHCLI_C_TYPE_INT32, mach.MACHCLI_SQL_TYPE_INT32, unsafe.Pointer(&uintValue), 4)
-	require.NoError(t, err, "bind fail")
-	longValue := int64(5) // long_value
-	err = mach.CliBindParam(stmt, 4, mach.MACHCLI_C_TYPE_INT64, mach.MACHCLI_SQL_TYPE_INT64, unsafe.Pointer(&longValue), 8)
-	require.NoError(t, err, "bind fail")
-	ulongValue := uint64(6) // ulong_value
-	err = mach.CliBindParam(stmt, 5, mach.MACHCLI_C_TYPE_INT64, mach.MACHCLI_SQL_TYPE_INT64, unsafe.Pointer(&ulongValue), 8)
-	require.NoError(t, err, "bind fail")
-	strValue := "str1" // str_value
-	err = mach.CliBindParam(stmt, 6, mach.MACHCLI_C_TYPE_CHAR, mach.MACHCLI_SQL_TYPE_STRING, unsafe.Pointer(&[]byte(strValue)[0]), len(strValue))
-	require.NoError(t, err, "bind fail")
-	jsonValue := `{"key1": "value1"}` // json_value
-	err = mach.CliBindParam(stmt, 7, mach.MACHCLI_C_TYPE_CHAR, mach.MACHCLI_SQL_TYPE_STRING, unsafe.Pointer(&[]byte(jsonValue)[0]), len(jsonValue))
-	require.NoError(t, err, "bind fail")
-	ipv4Value := net.IPv4(192, 168, 0, 1).To4().String() // ipv4_value
-	err = mach.CliBindParam(stmt, 8, mach.MACHCLI_C_TYPE_CHAR, mach.MACHCLI_SQL_TYPE_IPV4, unsafe.Pointer(&[]byte(ipv4Value)[0]), len(ipv4Value))
-	require.NoError(t, err, "bind fail")
-	ipv6Value := net.IPv6loopback.String() // ipv6_value
-	err = mach.CliBindParam(stmt, 9, mach.MACHCLI_C_TYPE_CHAR, mach.MACHCLI_SQL_TYPE_IPV6, unsafe.Pointer(&[]byte(ipv6Value)[0]), len(ipv6Value))
-	require.NoError(t, err, "bind fail")
+	values := []any{
+		int16(1),                 // short_value
+		uint16(2),                // ushort_value
+		int(3),                   // int_value
+		uint(4),                  // uint_value
+		int64(5),                 // long_value
+		uint64(6),                // ulong_value
+		"str1",                   // str_value
+		`{"key1": "value1"}`,     // json_value
+		net.IPv4(192, 168, 0, 1), // ipv4_value
+		net.IPv6loopback,         // ipv6_value
+	}
+	for i, v := range values {
+		err = mach.CliBind(stmt, i, v)
+		require.NoError(t, err, "bind fail")
+	}
 
 	// execute
 	err = mach.CliExecute(stmt)
@@ -3,6 +3,17 @@
 
 package mach
 
+import "golang.org/x/sys/unix"
+
 func translateCodePage(str string) string {
 	return str
 }
+
+// diskFreeSpace returns the available bytes of the file system that contains path.
+func diskFreeSpace(path string) (uint64, error) {
+	var st unix.Statfs_t
+	if err := unix.Statfs(path, &st); err != nil {
+		return 0, err
+	}
+	return uint64(st.Bavail) * uint64(st.Bsize), nil
+}
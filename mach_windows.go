@@ -21,3 +21,16 @@ func translateCodePage(str string) string {
 		return str
 	}
 }
+
+// diskFreeSpace returns the available bytes of the disk that contains path.
+func diskFreeSpace(path string) (uint64, error) {
+	pathPtr, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return 0, err
+	}
+	var freeBytes uint64
+	if err := windows.GetDiskFreeSpaceEx(pathPtr, &freeBytes, nil, nil); err != nil {
+		return 0, err
+	}
+	return freeBytes, nil
+}
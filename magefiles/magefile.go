@@ -10,7 +10,7 @@ import (
 
 func Test() error {
 	mg.Deps(CheckTmp)
-	if err := sh.RunV("go", "test", ".", "-count", "1"); err != nil {
+	if err := sh.RunV("go", "test", ".", "./trc", "-count", "1"); err != nil {
 		return err
 	}
 	fmt.Println("Test done.")
@@ -0,0 +1,156 @@
+package trc
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"time"
+)
+
+type FollowOptions struct {
+	// FromStart reads the existing content of the file,
+	// otherwise it starts from the end like 'tail -F'.
+	FromStart bool
+	// PollInterval is the interval to check new lines and rotation, default 250ms.
+	PollInterval time.Duration
+	// QuietPeriod is how long no line is written before the last record is passed to fn,
+	// since a record can continue in the next lines. It is 4 times of PollInterval
+	// if it is not longer than PollInterval.
+	QuietPeriod time.Duration
+}
+
+// Follow calls fn for every record that is written to the file at path until ctx ends.
+// When the file is rotated (replaced by a new file) or truncated,
+// it continues from the beginning of the new content.
+// The file does not need to exist when Follow is called.
+//
+// A record is passed to fn when the next record begins, or after QuietPeriod without new lines.
+// An incomplete line without newline is kept until the rest is written,
+// it is dropped when the file is rotated or truncated.
+func Follow(ctx context.Context, path string, opts FollowOptions, fn func(Record)) error {
+	interval := opts.PollInterval
+	if interval <= 0 {
+		interval = 250 * time.Millisecond
+	}
+	quiet := opts.QuietPeriod
+	if quiet <= interval {
+		quiet = 4 * interval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var file *os.File
+	var info os.FileInfo
+	var br *bufio.Reader
+	var offset int64
+	var partial string
+	var lastRead time.Time
+	var p parser
+	fromStart := opts.FromStart
+	defer func() {
+		if file != nil {
+			file.Close()
+		}
+	}()
+
+	// drain reads the complete lines that are available.
+	drain := func() {
+		for {
+			text, err := br.ReadString('\n')
+			offset += int64(len(text))
+			if err != nil {
+				// keep the incomplete line until the rest is written
+				partial += text
+				return
+			}
+			lastRead = time.Now()
+			if rec := p.line(partial + text); rec != nil {
+				fn(*rec)
+			}
+			partial = ""
+		}
+	}
+	// flush passes the pending record to fn, the incomplete line is not a record.
+	flush := func() {
+		if rec := p.flush(); rec != nil {
+			fn(*rec)
+		}
+	}
+
+	for {
+		if file == nil {
+			if f, err := os.Open(path); err == nil {
+				file = f
+				info, _ = f.Stat()
+				offset = 0
+				if !fromStart {
+					if offset, err = f.Seek(0, io.SeekEnd); err != nil {
+						return err
+					}
+				}
+				br = bufio.NewReader(f)
+				// a rotated file is always read from the beginning
+				fromStart = true
+			} else if !os.IsNotExist(err) {
+				return err
+			}
+		}
+		if file != nil {
+			drain()
+			if time.Since(lastRead) >= quiet {
+				flush()
+			}
+			if nfo, err := os.Stat(path); err == nil && info != nil && !os.SameFile(info, nfo) {
+				// rotated
+				drain()
+				flush()
+				partial = ""
+				file.Close()
+				file, info = nil, nil
+				continue
+			} else if err == nil && nfo.Size() < offset {
+				// truncated
+				flush()
+				partial = ""
+				if _, err := file.Seek(0, io.SeekStart); err != nil {
+					return err
+				}
+				br.Reset(file)
+				offset = 0
+			}
+		}
+		select {
+		case <-ctx.Done():
+			if file != nil {
+				drain()
+				flush()
+			}
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Forward sends the records that Follow() reads to the slog handler.
+// The module, process and thread of the record are added as attributes.
+func Forward(ctx context.Context, path string, opts FollowOptions, handler slog.Handler) error {
+	return Follow(ctx, path, opts, func(rec Record) {
+		level := rec.SlogLevel()
+		if !handler.Enabled(ctx, level) {
+			return
+		}
+		sr := slog.NewRecord(rec.Time, level, rec.Message, 0)
+		if rec.Module != "" {
+			sr.AddAttrs(slog.String("module", rec.Module))
+		}
+		if rec.Process != 0 {
+			sr.AddAttrs(slog.Int("pid", rec.Process))
+		}
+		if rec.Thread != 0 {
+			sr.AddAttrs(slog.Uint64("tid", rec.Thread))
+		}
+		handler.Handle(ctx, sr)
+	})
+}
@@ -0,0 +1,150 @@
+// Package trc reads the trace log files that the engine writes
+// into TRACE_LOGFILE_PATH (?/trc by default).
+package trc
+
+import (
+	"bufio"
+	"io"
+	"log/slog"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultFileName is the name of the trace log file of the engine.
+const DefaultFileName = "machbase.trc"
+
+// Record is an entry of the trace log.
+// Lines that follow the header line of an entry are appended to the Message.
+type Record struct {
+	Time    time.Time
+	Level   string
+	Module  string
+	Process int
+	Thread  uint64
+	Message string
+}
+
+// [2024-10-21 10:37:41 P-68017 T-6125793280][INFO][SM] message
+var headerRegexp = regexp.MustCompile(`^\[(\d{4}-\d{2}-\d{2}[ T]\d{2}:\d{2}:\d{2}(?:\.\d+)?)(?:\s+P-(\d+))?(?:\s+T-(\d+))?\]\s*\[([A-Za-z_]+)\](?:\s*\[([^\]]*)\])?\s?(.*)$`)
+
+var timeLayouts = []string{
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04:05.999999999",
+}
+
+// ParseLine parses the header line of a record, it returns false if line is not a header.
+func ParseLine(line string) (Record, bool) {
+	m := headerRegexp.FindStringSubmatch(line)
+	if m == nil {
+		return Record{}, false
+	}
+	ret := Record{Level: strings.ToUpper(m[4]), Module: m[5], Message: m[6]}
+	for _, layout := range timeLayouts {
+		if ts, err := time.ParseInLocation(layout, m[1], time.Local); err == nil {
+			ret.Time = ts
+			break
+		}
+	}
+	if m[2] != "" {
+		ret.Process, _ = strconv.Atoi(m[2])
+	}
+	if m[3] != "" {
+		ret.Thread, _ = strconv.ParseUint(m[3], 10, 64)
+	}
+	return ret, true
+}
+
+// SlogLevel maps the level of the record to slog.Level.
+func (rec Record) SlogLevel() slog.Level {
+	switch {
+	case strings.HasPrefix(rec.Level, "ERR"), strings.HasPrefix(rec.Level, "FATAL"):
+		return slog.LevelError
+	case strings.HasPrefix(rec.Level, "WARN"):
+		return slog.LevelWarn
+	case strings.HasPrefix(rec.Level, "DEBUG"), strings.HasPrefix(rec.Level, "TRACE"):
+		return slog.LevelDebug
+	default:
+		return slog.LevelInfo
+	}
+}
+
+// parser merges continuation lines into the pending record.
+type parser struct {
+	pending *Record
+}
+
+// line returns the record that is completed by the line, or nil.
+func (p *parser) line(text string) *Record {
+	text = strings.TrimRight(text, "\r\n")
+	if rec, ok := ParseLine(text); ok {
+		ret := p.pending
+		p.pending = &rec
+		return ret
+	}
+	if p.pending == nil {
+		if strings.TrimSpace(text) == "" {
+			return nil
+		}
+		p.pending = &Record{Message: text}
+		return nil
+	}
+	p.pending.Message += "\n" + text
+	return nil
+}
+
+func (p *parser) flush() *Record {
+	ret := p.pending
+	p.pending = nil
+	return ret
+}
+
+// Reader reads the records from a trace log.
+type Reader struct {
+	br     *bufio.Reader
+	parser parser
+	err    error
+}
+
+func NewReader(r io.Reader) *Reader {
+	return &Reader{br: bufio.NewReader(r)}
+}
+
+// Next returns the next record, io.EOF at the end.
+func (r *Reader) Next() (Record, error) {
+	for r.err == nil {
+		var text string
+		text, r.err = r.br.ReadString('\n')
+		if text != "" {
+			if rec := r.parser.line(text); rec != nil {
+				return *rec, nil
+			}
+		}
+	}
+	if rec := r.parser.flush(); rec != nil {
+		return *rec, nil
+	}
+	return Record{}, r.err
+}
+
+// ReadFile returns all records of the trace log file.
+func ReadFile(path string) ([]Record, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	ret := []Record{}
+	r := NewReader(f)
+	for {
+		rec, err := r.Next()
+		if err == io.EOF {
+			return ret, nil
+		} else if err != nil {
+			return ret, err
+		}
+		ret = append(ret, rec)
+	}
+}
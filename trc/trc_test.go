@@ -0,0 +1,167 @@
+package trc_test
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/machbase/neo-engine/v8/trc"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLine(t *testing.T) {
+	rec, ok := trc.ParseLine("[2024-10-21 10:37:41 P-68017 T-6125793280][INFO][SM] startup done")
+	require.True(t, ok)
+	require.Equal(t, time.Date(2024, 10, 21, 10, 37, 41, 0, time.Local), rec.Time)
+	require.Equal(t, "INFO", rec.Level)
+	require.Equal(t, "SM", rec.Module)
+	require.Equal(t, 68017, rec.Process)
+	require.Equal(t, uint64(6125793280), rec.Thread)
+	require.Equal(t, "startup done", rec.Message)
+	require.Equal(t, slog.LevelInfo, rec.SlogLevel())
+
+	rec, ok = trc.ParseLine("[2024-10-21 10:37:41.123456][error] failed")
+	require.True(t, ok)
+	require.Equal(t, 123456000, rec.Time.Nanosecond())
+	require.Equal(t, "", rec.Module)
+	require.Equal(t, slog.LevelError, rec.SlogLevel())
+
+	_, ok = trc.ParseLine("  continued line")
+	require.False(t, ok)
+}
+
+func TestReader(t *testing.T) {
+	text := strings.Join([]string{
+		"orphan line",
+		"[2024-10-21 10:37:41][WARN] first",
+		"  detail 1",
+		"  detail 2",
+		"[2024-10-21 10:37:42][INFO] second",
+	}, "\n")
+	r := trc.NewReader(strings.NewReader(text))
+	recs := []trc.Record{}
+	for {
+		rec, err := r.Next()
+		if err != nil {
+			break
+		}
+		recs = append(recs, rec)
+	}
+	require.Len(t, recs, 3)
+	require.Equal(t, "orphan line", recs[0].Message)
+	require.Equal(t, "first\n  detail 1\n  detail 2", recs[1].Message)
+	require.Equal(t, "WARN", recs[1].Level)
+	require.Equal(t, "second", recs[2].Message)
+}
+
+type recorder struct {
+	sync.Mutex
+	msgs []string
+}
+
+func (r *recorder) add(rec trc.Record) {
+	r.Lock()
+	r.msgs = append(r.msgs, rec.Message)
+	r.Unlock()
+}
+
+func (r *recorder) get() []string {
+	r.Lock()
+	defer r.Unlock()
+	return append([]string{}, r.msgs...)
+}
+
+func TestFollow(t *testing.T) {
+	path := filepath.Join(t.TempDir(), trc.DefaultFileName)
+	require.NoError(t, os.WriteFile(path, []byte("[2024-10-21 10:00:00][INFO] old\n"), 0644))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	rec := &recorder{}
+	done := make(chan error)
+	go func() {
+		done <- trc.Follow(ctx, path, trc.FollowOptions{PollInterval: 10 * time.Millisecond}, rec.add)
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	appendLine := func(line string) {
+		f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+		require.NoError(t, err)
+		f.WriteString(line + "\n")
+		f.Close()
+	}
+	appendLine("[2024-10-21 10:00:01][INFO] one")
+	require.Eventually(t, func() bool { return len(rec.get()) == 1 }, time.Second, 10*time.Millisecond)
+
+	// rotation
+	require.NoError(t, os.Rename(path, path+".1"))
+	require.NoError(t, os.WriteFile(path, []byte("[2024-10-21 10:00:02][INFO] two\n"), 0644))
+	require.Eventually(t, func() bool { return len(rec.get()) == 2 }, time.Second, 10*time.Millisecond)
+
+	// truncation
+	require.NoError(t, os.WriteFile(path, []byte("[2024-10-21 10:00:03][INFO] 3\n"), 0644))
+	require.Eventually(t, func() bool { return len(rec.get()) == 3 }, time.Second, 10*time.Millisecond)
+
+	cancel()
+	require.ErrorIs(t, <-done, context.Canceled)
+	require.Equal(t, []string{"one", "two", "3"}, rec.get())
+}
+
+func TestFollowMultiLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), trc.DefaultFileName)
+	require.NoError(t, os.WriteFile(path, nil, 0644))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	rec := &recorder{}
+	done := make(chan error)
+	go func() {
+		done <- trc.Follow(ctx, path, trc.FollowOptions{PollInterval: 10 * time.Millisecond, QuietPeriod: 300 * time.Millisecond}, rec.add)
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	write := func(text string) {
+		f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+		require.NoError(t, err)
+		f.WriteString(text)
+		f.Close()
+	}
+	// the record continues in the next write, and the last line is incomplete
+	write("[2024-10-21 10:00:01][ERROR] first\n")
+	time.Sleep(50 * time.Millisecond)
+	write("stack line\n[2024-10-21 10:00:02][INFO] sec")
+	time.Sleep(50 * time.Millisecond)
+	require.Empty(t, rec.get())
+	write("ond\n")
+	require.Eventually(t, func() bool { return len(rec.get()) == 2 }, time.Second, 10*time.Millisecond)
+	require.Equal(t, []string{"first\nstack line", "second"}, rec.get())
+
+	// an incomplete line is not passed even after the quiet period
+	write("[2024-10-21 10:00:03][INFO] partial")
+	time.Sleep(400 * time.Millisecond)
+	cancel()
+	require.ErrorIs(t, <-done, context.Canceled)
+	require.Equal(t, []string{"first\nstack line", "second"}, rec.get())
+}
+
+func TestForward(t *testing.T) {
+	path := filepath.Join(t.TempDir(), trc.DefaultFileName)
+	require.NoError(t, os.WriteFile(path, []byte(
+		"[2024-10-21 10:00:00 P-1 T-2][ERROR][SM] broken\n"+
+			"[2024-10-21 10:00:01][DEBUG] hidden\n"), 0644))
+
+	buf := &bytes.Buffer{}
+	handler := slog.NewTextHandler(buf, &slog.HandlerOptions{Level: slog.LevelInfo})
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	err := trc.Forward(ctx, path, trc.FollowOptions{FromStart: true, PollInterval: 10 * time.Millisecond}, handler)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	out := buf.String()
+	require.Contains(t, out, "level=ERROR msg=broken module=SM pid=1 tid=2")
+	require.NotContains(t, out, "hidden")
+}